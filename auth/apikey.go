@@ -0,0 +1,52 @@
+package auth
+
+import "strings"
+
+// scopes of a personal API key
+const (
+	ScopeRead         = "read"          // GET requests only
+	ScopeWriteCafes   = "write:cafes"   // create / edit / delete cafes
+	ScopeWriteRatings = "write:ratings" // rate cafes, edit / delete ratings
+)
+
+// every API key starts with this, so it is easy to find in logs and configs
+const apiKeyPrefix = "cfy_"
+
+var validScopes = []string{ScopeRead, ScopeWriteCafes, ScopeWriteRatings}
+
+// GenerateAPIKey returns the full key (shown once), a short prefix for the
+// list page, and the hash that is saved in the database
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + token
+	return key, key[:len(apiKeyPrefix)+6], HashToken(key), nil
+}
+
+// ValidScope checks if the scope exists
+func ValidScope(scope string) bool {
+	for _, s := range validScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope checks the scope list of a key ("read,write:cafes").
+// write scopes also allow reading, a script that writes cafes needs to list them too
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Split(scopes, ",") {
+		s = strings.TrimSpace(s)
+		if s == scope {
+			return true
+		}
+		if scope == ScopeRead && strings.HasPrefix(s, "write:") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// kid of the key made from JWT_SECRET, also used for old tokens without "kid" header
+const legacyKeyID = "default"
+
+// SigningKey is one key of the key set
+type SigningKey struct {
+	ID     string // "kid" header
+	Method jwt.SigningMethod
+
+	// sign: []byte (HS256), *rsa.PrivateKey (RS256), ed25519.PrivateKey (EdDSA).
+	// nil if we only have the public key (verify only)
+	signKey interface{}
+
+	// verify: []byte (HS256), *rsa.PublicKey (RS256), ed25519.PublicKey (EdDSA)
+	verifyKey interface{}
+}
+
+// KeySet holds every key that is accepted for verification,
+// and the one key that is used to sign new tokens
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+var (
+	keySet     *KeySet
+	keySetOnce sync.Once
+)
+
+// LoadSigningKeys reads the keys from .env, call it once at startup.
+//
+//	JWT_SECRET          -> HS256 key with kid "default" (old setup, still works)
+//	JWT_KEYS_DIR        -> folder with one file per key, the file name is the kid:
+//	                       <kid>.secret (HS256 secret), <kid>.pem (RSA / Ed25519 PEM,
+//	                       private key = sign + verify, public key = verify only)
+//	JWT_SIGNING_KEY_ID  -> kid used to sign new tokens (default "default")
+//
+// To rotate: add the new key file, point JWT_SIGNING_KEY_ID to it and keep the
+// old file until the old tokens are expired (REFRESH_TOKEN_TTL is not a JWT, so
+// that is only ACCESS_TOKEN_TTL).
+func LoadSigningKeys() {
+	keySetOnce.Do(func() {
+		set, err := loadKeySet()
+		if err != nil {
+			log.Fatal("Failed to load JWT signing keys: ", err)
+		}
+		keySet = set
+	})
+}
+
+func currentKeySet() *KeySet {
+	LoadSigningKeys()
+	return keySet
+}
+
+func loadKeySet() (*KeySet, error) {
+	set := &KeySet{keys: map[string]*SigningKey{}}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		set.keys[legacyKeyID] = hmacKey(legacyKeyID, []byte(secret))
+	}
+
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			kid := strings.TrimSuffix(name, ext)
+
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+
+			var key *SigningKey
+			switch ext {
+			case ".secret":
+				key = hmacKey(kid, []byte(strings.TrimSpace(string(data))))
+			case ".pem":
+				key, err = pemKey(kid, data)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+			default:
+				continue
+			}
+
+			set.keys[kid] = key
+		}
+	}
+
+	activeID := os.Getenv("JWT_SIGNING_KEY_ID")
+	if activeID == "" {
+		activeID = legacyKeyID
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found (set JWT_SECRET or JWT_KEYS_DIR)", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("signing key %q is a public key, it can't sign", activeID)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+func hmacKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// parse an RSA or Ed25519 key from PEM (private or public)
+func pemKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T (only RSA and Ed25519)", parsed)
+}
+
+// sign the claims with the active key and put its kid in the header
+func signClaims(claims jwt.MapClaims) (string, error) {
+	key := currentKeySet().active
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signKey)
+}
+
+// jwt.Keyfunc: find the verification key by the "kid" header
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// token from before key rotation was added
+		kid = legacyKeyID
+	}
+
+	key, ok := currentKeySet().keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	// the alg in the header must match the key, otherwise someone could
+	// e.g. sign with HS256 using the public RSA key as secret
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public keys (RSA / Ed25519 only, never the HMAC secrets)
+// in JSON Web Key Set format for /.well-known/jwks.json
+func JWKS() map[string]interface{} {
+	set := currentKeySet()
+
+	kids := make([]string, 0, len(set.keys))
+	for kid := range set.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := []map[string]string{}
+	for _, kid := range kids {
+		key := set.keys[kid]
+
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": key.Method.Alg(),
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"use": "sig",
+				"alg": key.Method.Alg(),
+				"kid": kid,
+				"x":   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
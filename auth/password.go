@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordPolicy is read from .env:
+//
+//	PASSWORD_MIN_LENGTH=8
+//	PASSWORD_MIN_CLASSES=2               (lowercase, uppercase, digit, symbol)
+//	BREACHED_PASSWORDS_PATH=./pwned      (optional, see isBreached)
+//
+// the bcrypt cost is separate: BCRYPT_COST=10 (see HashPassword)
+type PasswordPolicy struct {
+	MinLength    int
+	MinClasses   int
+	BreachedPath string
+}
+
+// bcrypt ignores everything after 72 bytes
+const maxPasswordBytes = 72
+
+func PasswordPolicyFromEnv() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    envInt("PASSWORD_MIN_LENGTH", 8),
+		MinClasses:   envInt("PASSWORD_MIN_CLASSES", 2),
+		BreachedPath: os.Getenv("BREACHED_PASSWORDS_PATH"),
+	}
+}
+
+// Check returns every rule the password breaks (empty = ok)
+func (p PasswordPolicy) Check(password string) []string {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("Password minimal %d karakter", p.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		problems = append(problems, fmt.Sprintf("Password maksimal %d byte", maxPasswordBytes))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		problems = append(problems, fmt.Sprintf("Password harus memakai minimal %d jenis karakter (huruf kecil, huruf besar, angka, simbol)", p.MinClasses))
+	}
+
+	if p.BreachedPath != "" && password != "" {
+		breached, err := isBreached(p.BreachedPath, password)
+		if err != nil {
+			// the password is accepted, but a wrong path must not switch the check off unnoticed
+			log.Println("breached password check failed (BREACHED_PASSWORDS_PATH):", err)
+		}
+		if breached {
+			problems = append(problems, "Password ini pernah bocor di data breach, pilih password lain")
+		}
+	}
+
+	return problems
+}
+
+// HashPassword hashes with the configured bcrypt cost
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+	return string(hash), err
+}
+
+// NeedsRehash is true when the hash was made with another cost than BCRYPT_COST
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost != bcryptCost()
+}
+
+func bcryptCost() int {
+	cost := envInt("BCRYPT_COST", bcrypt.DefaultCost)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return cost
+}
+
+// isBreached looks up the SHA-1 of the password in a local copy of a breached
+// password list (Have I Been Pwned format, "HASH:COUNT" per line, uppercase hex).
+//
+// path can be
+//   - a folder with k-anonymity range files: one file per 5 char prefix
+//     (e.g. "21BD1" or "21BD1.txt") with "SUFFIX:COUNT" lines.
+//     only the one small range file is read per check
+//   - one file with full "HASH:COUNT" lines, read once into memory (see loadBreachedList)
+func isBreached(path, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	list, err := loadBreachedList(path)
+	if err != nil {
+		return false, err
+	}
+	if list != nil {
+		return list.contains(sum), nil
+	}
+
+	prefix, suffix := hash[:5], hash[5:]
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		found, err := fileContainsHash(filepath.Join(path, name), suffix)
+		if err == nil {
+			return found, nil
+		}
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// sorted SHA-1 hashes of one breached password file
+type breachedList [][sha1.Size]byte
+
+func (l breachedList) contains(sum [sha1.Size]byte) bool {
+	i := sort.Search(len(l), func(i int) bool { return bytes.Compare(l[i][:], sum[:]) >= 0 })
+	return i < len(l) && l[i] == sum
+}
+
+var (
+	breachedMu    sync.Mutex
+	breachedLists = map[string]breachedList{} // path -> list, nil for folders
+)
+
+// LoadBreachedPasswords reads BREACHED_PASSWORDS_PATH at startup, so the first
+// register doesn't wait for it and a wrong path shows up in the log right away
+func LoadBreachedPasswords() {
+	if path := os.Getenv("BREACHED_PASSWORDS_PATH"); path != "" {
+		if _, err := loadBreachedList(path); err != nil {
+			log.Println("breached password list not loaded (BREACHED_PASSWORDS_PATH):", err)
+		}
+	}
+}
+
+// the list of a single file path, loaded on the first call and then kept.
+// folders return nil, their range files are read per lookup.
+// errors are not cached, so a fixed path works without restart
+func loadBreachedList(path string) (breachedList, error) {
+	breachedMu.Lock()
+	defer breachedMu.Unlock()
+
+	if list, ok := breachedLists[path]; ok {
+		return list, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var list breachedList
+	if !info.IsDir() {
+		if list, err = readBreachedFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	breachedLists[path] = list
+	return list, nil
+}
+
+func readBreachedFile(path string) (breachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := breachedList{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+
+		var sum [sha1.Size]byte
+		if n, err := hex.Decode(sum[:], []byte(line)); err != nil || n != sha1.Size {
+			continue // empty or broken line
+		}
+		list = append(list, sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool { return bytes.Compare(list[i][:], list[j][:]) < 0 })
+	return list, nil
+}
+
+func fileContainsHash(path, hash string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, hash) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SHA-1 of "password" and "P@ssw0rd"
+const (
+	sha1Password = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	sha1PAssw0rd = "21BD12DC183F740EE76F27B78EB39C8AD972A757"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 2}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"ok", "kopisusu12", nil},
+		{"too short", "kopi1", []string{"Password minimal 8 karakter"}},
+		{"length counts characters, not bytes", "kopi☕☕☕☕", nil},
+		{"one class only", "kopisusugula", []string{"Password harus memakai minimal 2 jenis karakter (huruf kecil, huruf besar, angka, simbol)"}},
+		{"symbol counts as a class", "kopi susu", nil},
+		{"over the bcrypt limit", "Aa" + string(make([]byte, 71)), []string{"Password maksimal 72 byte"}},
+		{"empty", "", []string{
+			"Password minimal 8 karakter",
+			"Password harus memakai minimal 2 jenis karakter (huruf kecil, huruf besar, angka, simbol)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Check(tt.password); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check(%q) = %q, want %q", tt.password, got, tt.want)
+			}
+		})
+	}
+
+	strict := PasswordPolicy{MinLength: 8, MinClasses: 4}
+	if got := strict.Check("Kopisusu12!"); len(got) != 0 {
+		t.Errorf("all four classes: %q", got)
+	}
+	if got := strict.Check("Kopisusu12"); len(got) != 1 {
+		t.Errorf("three of four classes: %q", got)
+	}
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "pwned.txt")
+	os.WriteFile(file, []byte(sha1PAssw0rd+":74283\n\n"+"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:9545824\nnot a hash\n"), 0o600)
+
+	ranges := filepath.Join(dir, "ranges")
+	os.Mkdir(ranges, 0o700)
+	os.WriteFile(filepath.Join(ranges, sha1PAssw0rd[:5]+".txt"), []byte(sha1PAssw0rd[5:]+":74283\r\n"), 0o600)
+	os.WriteFile(filepath.Join(ranges, sha1Password[:5]), []byte("0000000000000000000000000000000000A:1\n"), 0o600)
+
+	tests := []struct {
+		name     string
+		path     string
+		password string
+		want     bool
+		wantErr  bool
+	}{
+		{"file, uppercase line", file, "P@ssw0rd", true, false},
+		{"file, lowercase line", file, "password", true, false},
+		{"file, not listed", file, "kopi susu 12", false, false},
+		{"folder, .txt range file", ranges, "P@ssw0rd", true, false},
+		{"folder, range file without the suffix", ranges, "password", false, false},
+		{"folder, no range file", ranges, "kopi susu 12", false, false},
+		{"missing path", filepath.Join(dir, "missing"), "password", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isBreached(tt.path, tt.password)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("isBreached = %v, %v, want %v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	// the file is read once: removing it afterwards doesn't change the answer
+	os.Remove(file)
+	if got, err := isBreached(file, "P@ssw0rd"); !got || err != nil {
+		t.Errorf("cached list = %v, %v", got, err)
+	}
+
+	// breached passwords are reported by the policy
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 2, BreachedPath: ranges}
+	if got := policy.Check("P@ssw0rd"); len(got) != 1 || got[0] != "Password ini pernah bocor di data breach, pilih password lain" {
+		t.Errorf("Check = %q", got)
+	}
+}
+
+func TestHashPasswordAndRehash(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "5")
+
+	hash, err := HashPassword("kopisusu12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != 5 {
+		t.Errorf("cost = %d, want 5", cost)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte("kopisusu12")) != nil {
+		t.Error("hash doesn't match the password")
+	}
+	if NeedsRehash(hash) {
+		t.Error("hash with the current cost needs no rehash")
+	}
+
+	// cost raised -> old hashes are rehashed on the next login
+	t.Setenv("BCRYPT_COST", "6")
+	if !NeedsRehash(hash) {
+		t.Error("hash with an old cost should be rehashed")
+	}
+
+	// out of range -> bcrypt default
+	t.Setenv("BCRYPT_COST", "99")
+	if got := bcryptCost(); got != bcrypt.DefaultCost {
+		t.Errorf("bcryptCost = %d, want default", got)
+	}
+
+	// not a bcrypt hash (e.g. empty password of an OIDC-only account)
+	if NeedsRehash("") {
+		t.Error("empty hash can't be rehashed")
+	}
+}
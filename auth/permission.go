@@ -0,0 +1,34 @@
+package auth
+
+import "github.com/rizqy/cafetify/models"
+
+// permissions that are checked by middleware.RequirePermission
+// and by the controllers for "owner or moderator" checks
+const (
+	PermModerateContent = "content:moderate"    // edit / delete cafes, ratings and tags of other users
+	PermReadAuditLog    = "moderation_log:read" // see what moderators did
+	PermManageRoles     = "users:manage_roles"  // promote / demote users
+)
+
+// which role has which permission
+var rolePermissions = map[string][]string{
+	models.RoleUser:      {},
+	models.RoleModerator: {PermModerateContent, PermReadAuditLog},
+	models.RoleAdmin:     {PermModerateContent, PermReadAuditLog, PermManageRoles},
+}
+
+// HasPermission checks if the role has the permission
+func HasPermission(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRole checks if the role exists
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// where the access token of a request came from
+const (
+	SourceCookie = "cookie" // browser, needs CSRF protection
+	SourceBearer = "bearer" // Authorization header, can't be sent by another site
+)
+
+// TokenFromRequest gets the access token from the "Authorization" cookie,
+// or from the Authorization header ("Bearer <token>") if there is no cookie.
+// the second value is SourceCookie or SourceBearer
+func TokenFromRequest(c *gin.Context) (string, string) {
+	// get token from cookie
+	if tokenString, _ := c.Cookie("Authorization"); tokenString != "" {
+		return tokenString, SourceCookie
+	}
+
+	// if cookie is empty, check Authorization header
+	authHeader := c.GetHeader("Authorization")
+
+	// format for header: "Bearer <token>"
+	// get the token part only
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	tokenString = strings.TrimSpace(tokenString)
+
+	return tokenString, SourceBearer
+}
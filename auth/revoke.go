@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+// RevokeToken puts the jti of an access token in the revocation list.
+// the row is only needed until the token would expire anyway
+func RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+
+	// cleanup old rows so the table doesn't grow forever
+	initializers.DB.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+
+	return initializers.DB.
+		Where(models.RevokedToken{JTI: jti}).
+		FirstOrCreate(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// IsRevoked checks if the jti is in the revocation list
+func IsRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+
+	var count int64
+	initializers.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count)
+	return count > 0
+}
+
+// RevokeAllForUser logs the user out everywhere: every access token issued
+// before now becomes invalid and every session + refresh token is revoked
+func RevokeAllForUser(userID uint) error {
+	now := time.Now()
+
+	if err := initializers.DB.Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("tokens_revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	if err := initializers.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	return initializers.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// IssuedBeforeRevocation returns true if the token was issued before the user
+// pressed "log out all sessions" (or changed the password).
+// iat only has seconds, so a token from the same second as the revocation is
+// treated as revoked too: it could be an old token of that second
+func IssuedBeforeRevocation(user models.User, issuedAt time.Time) bool {
+	if user.TokensRevokedAt == nil {
+		return false
+	}
+	issued := issuedAt.Truncate(time.Second)
+	revoked := user.TokensRevokedAt.Truncate(time.Second)
+	return issued.Before(revoked) || issued.Equal(revoked)
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rizqy/cafetify/models"
+)
+
+func TestIssuedBeforeRevocation(t *testing.T) {
+	revokedAt := time.Date(2026, 10, 18, 12, 0, 0, 700_000_000, time.UTC) // 12:00:00.7
+	user := models.User{TokensRevokedAt: &revokedAt}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"a second before", revokedAt.Add(-time.Second), true},
+		{"same second, earlier (iat has no fraction)", time.Unix(revokedAt.Unix(), 0), true},
+		{"same second, later fraction", revokedAt.Add(200 * time.Millisecond), true},
+		{"next second", time.Unix(revokedAt.Unix()+1, 0), false},
+		{"long after", revokedAt.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IssuedBeforeRevocation(user, tt.issuedAt); got != tt.want {
+				t.Errorf("IssuedBeforeRevocation(%s) = %v, want %v", tt.issuedAt, got, tt.want)
+			}
+		})
+	}
+
+	if IssuedBeforeRevocation(models.User{}, revokedAt) {
+		t.Error("a user that never revoked has no revoked tokens")
+	}
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// default lifetime of the tokens, can be changed from .env
+// (ACCESS_TOKEN_TTL=15m, REFRESH_TOKEN_TTL=720h)
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// time to type the 2FA code after the password was correct
+	mfaTokenTTL = 5 * time.Minute
+)
+
+// AccessTokenTTL is how long a JWT access token is valid.
+// keep it short, the client uses /refresh to get a new one
+func AccessTokenTTL() time.Duration {
+	return envDuration("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL is how long a refresh token can be used
+func RefreshTokenTTL() time.Duration {
+	return envDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+// GenerateAccessToken creates a signed access token for the user (with the active key, see keys.go).
+// sessionID is the login session the token belongs to
+func GenerateAccessToken(userID, sessionID uint) (string, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+
+	return signClaims(jwt.MapClaims{
+		"sub": userID,                                  // Subject (ID User)
+		"exp": time.Now().Add(AccessTokenTTL()).Unix(), // short lived
+		"iat": time.Now().Unix(),
+		"sid": sessionID,
+		"typ": "access",
+		"jti": jti, // unique id, used by the revocation list on logout
+	})
+}
+
+// ParseAccessToken validates the signature + expiration and returns the claims.
+// an expired token returns an error that wraps jwt.ErrTokenExpired
+func ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return parseToken(tokenString, "access")
+}
+
+// GenerateMFAToken creates the short lived token returned by /login when the
+// user has 2FA enabled. it can only be exchanged at /login/mfa, not used as access token
+func GenerateMFAToken(userID uint) (string, error) {
+	return signClaims(jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(mfaTokenTTL).Unix(),
+		"iat": time.Now().Unix(),
+		"typ": "mfa",
+	})
+}
+
+// ParseMFAToken validates a token made by GenerateMFAToken
+func ParseMFAToken(tokenString string) (jwt.MapClaims, error) {
+	return parseToken(tokenString, "mfa")
+}
+
+func parseToken(tokenString, typ string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, verificationKey,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// an mfa token must not be accepted as access token (and the other way around)
+	if t, _ := claims["typ"].(string); t != typ {
+		return nil, errors.New("wrong token type")
+	}
+
+	return claims, nil
+}
+
+// NewOpaqueToken returns a random url-safe string (32 bytes of entropy).
+// used for refresh tokens, reset links etc.
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken hashes an opaque token before it is saved to the database,
+// so a leaked table can't be used to log in
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClaimUint reads a numeric claim ("sub", "sid") as uint, 0 if missing
+func ClaimUint(claims jwt.MapClaims, key string) uint {
+	v, ok := claims[key].(float64)
+	if !ok || v < 0 {
+		return 0
+	}
+	return uint(v)
+}
+
+// TokenExpiry reads the "exp" claim as time.Time
+func TokenExpiry(claims jwt.MapClaims) time.Time {
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Now().Add(AccessTokenTTL())
+	}
+	return exp.Time
+}
+
+func newJTI() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
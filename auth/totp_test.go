@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// "12345678901234567890", the SHA-1 key of the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B, last 6 of the 8 digits
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, tt.unix/totpPeriod)
+		if err != nil || got != tt.want {
+			t.Errorf("TOTPCode at %d = %q, %v, want %q", tt.unix, got, err, tt.want)
+		}
+	}
+
+	// secrets are often typed lowercase / with spaces around
+	if got, _ := TOTPCode(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", 1); got != "287082" {
+		t.Errorf("lowercase secret = %q", got)
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("invalid secret should fail")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	code := func(s int64) string {
+		c, _ := TOTPCode(rfcSecret, s)
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(step), 0, step, true},
+		{"previous step (clock drift)", code(step - 1), 0, step - 1, true},
+		{"next step (clock drift)", code(step + 1), 0, step + 1, true},
+		{"too old", code(step - 2), 0, 0, false},
+		{"too new", code(step + 2), 0, 0, false},
+		{"spaces are ignored", code(step)[:3] + " " + code(step)[3:], 0, step, true},
+		{"already used", code(step), step, 0, false},
+		{"older than the last used", code(step - 1), step - 1, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+		{"too short", code(step)[:5], 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	a, err1 := GenerateTOTPSecret()
+	b, err2 := GenerateTOTPSecret()
+	if err1 != nil || err2 != nil || a == b {
+		t.Fatalf("secrets %q, %q, %v, %v", a, b, err1, err2)
+	}
+	// 20 bytes = 32 base32 characters, usable by TOTPCode
+	if _, err := TOTPCode(a, 1); err != nil || len(a) != 32 {
+		t.Errorf("secret %q: %v", a, err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("Cafetify", "budi@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Cafetify:budi@example.com" ||
+		q.Get("secret") != rfcSecret || q.Get("issuer") != "Cafetify" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("unexpected uri %s", u)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes = %v, %v", codes, err)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-9]{4}-[a-z2-9]{4}-[a-z2-9]{4}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !format.MatchString(code) || seen[code] {
+			t.Errorf("bad or duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	if NormalizeRecoveryCode("K3F9 2HD8-Q1ZX") != NormalizeRecoveryCode("k3f9-2hd8-q1zx") {
+		t.Error("normalized codes differ")
+	}
+}
@@ -0,0 +1,144 @@
+// Command mocks3 is a tiny S3 compatible server for local development and tests,
+// so STORAGE=s3 can be tried without MinIO or an AWS account.
+//
+//	go run ./cmd/mocks3 -addr :9000 -dir mocks3-data
+//
+// and in .env:
+//
+//	STORAGE=s3
+//	S3_ENDPOINT=http://localhost:9000
+//	S3_BUCKET=cafetify
+//	S3_ACCESS_KEY=mock
+//	S3_SECRET_KEY=mocksecret
+//
+// Only PUT, GET and DELETE of single objects (path style) are supported.
+// Writes must have a valid signature v4, reads are public like a public bucket.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rizqy/cafetify/storage"
+)
+
+type server struct {
+	dir       string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	dir := flag.String("dir", "mocks3-data", "folder for the objects (one sub folder per bucket)")
+	region := flag.String("region", "us-east-1", "region used in the signature")
+	accessKey := flag.String("access-key", "mock", "accepted access key")
+	secretKey := flag.String("secret-key", "mocksecret", "secret of the access key")
+	flag.Parse()
+
+	s := &server{dir: *dir, region: *region, accessKey: *accessKey, secretKey: *secretKey}
+
+	log.Printf("mock S3 listening on %s, objects in %s", *addr, *dir)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.objectPath(r.URL.Path)
+	if !ok {
+		s3Error(w, http.StatusBadRequest, "InvalidURI", "use /<bucket>/<key>")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		http.ServeFile(w, r, path)
+
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		if !s.checkSignature(w, r, body) {
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			s3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		if err := os.WriteFile(path, body, 0o644); err != nil {
+			s3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodDelete:
+		if !s.checkSignature(w, r, nil) {
+			return
+		}
+		os.Remove(path)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// sign the same request again with our secret and compare
+func (s *server) checkSignature(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil || time.Since(signedAt).Abs() > 15*time.Minute {
+		s3Error(w, http.StatusForbidden, "RequestTimeTooSkewed", "missing or old X-Amz-Date")
+		return false
+	}
+
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		s3Error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "body does not match the signed hash")
+		return false
+	}
+
+	expected := &http.Request{
+		Method: r.Method,
+		URL:    &url.URL{Host: r.Host, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery},
+		Header: http.Header{},
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		expected.Header.Set("Content-Type", ct)
+	}
+	storage.SignV4(expected, payloadHash, s.accessKey, s.secretKey, s.region, signedAt)
+
+	if expected.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		s3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", "wrong access key, secret or signature")
+		return false
+	}
+	return true
+}
+
+// /<bucket>/<key> -> file in dir, never outside of it
+func (s *server) objectPath(urlPath string) (string, bool) {
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(urlPath, "/")))
+	parts := strings.SplitN(filepath.ToSlash(clean), "/", 2)
+	if len(parts) != 2 || parts[0] == ".." || strings.HasPrefix(clean, "..") {
+		return "", false
+	}
+	return filepath.Join(s.dir, clean), true
+}
+
+func s3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, "<Error><Code>"+code+"</Code><Message>"+html.EscapeString(message)+"</Message></Error>")
+}
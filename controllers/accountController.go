@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// default time between "delete my account" and the real deletion
+const defaultDeletionGrace = 14 * 24 * time.Hour
+
+func deletionGrace() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("ACCOUNT_DELETION_GRACE")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultDeletionGrace
+}
+
+// ---------------------------
+// EXPORT PERSONAL DATA
+// ---------------------------
+// zip file with one JSON file per kind of data
+func ExportAccount(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var cafes []models.Cafe
+	if err := initializers.DB.Preload("Tags").Where("user_id = ?", currentUser.ID).Find(&cafes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+		return
+	}
+
+	var ratings []models.PersonalRating
+	if err := initializers.DB.Where("user_id = ?", currentUser.ID).Find(&ratings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data rating"})
+		return
+	}
+
+	// tags on the cafes the user created or rated
+	var tags []models.Tag
+	if err := initializers.DB.Distinct("tags.*").
+		Joins("JOIN cafe_tags ON cafe_tags.tag_id = tags.id").
+		Where("cafe_tags.cafe_id IN (?) OR cafe_tags.cafe_id IN (?)",
+			initializers.DB.Model(&models.Cafe{}).Select("id").Where("user_id = ?", currentUser.ID),
+			initializers.DB.Model(&models.PersonalRating{}).Select("cafe_id").Where("user_id = ?", currentUser.ID)).
+		Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data tag"})
+		return
+	}
+
+	var photos []models.Photo
+	if err := initializers.DB.Where("user_id = ?", currentUser.ID).Find(&photos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data foto"})
+		return
+	}
+
+	var identities []models.LinkedIdentity
+	if err := initializers.DB.Where("user_id = ?", currentUser.ID).Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data akun tertaut"})
+		return
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", currentUser.Account()},
+		{"cafes.json", cafes},
+		{"ratings.json", ratings},
+		{"tags.json", tags},
+		{"identities.json", identities},
+		{"photos.json", photos},
+	}
+
+	filename := fmt.Sprintf("cafetify-export-%d-%s.zip", currentUser.ID, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	archive.Close()
+}
+
+// ---------------------------
+// DELETE ACCOUNT (with grace period)
+// ---------------------------
+func DeleteAccount(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var body struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code"` // 2FA code, only if 2FA is on
+	}
+
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password wajib diisi"})
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(currentUser.Password), []byte(body.Password)) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password salah"})
+		return
+	}
+
+	if currentUser.TOTPEnabledAt != nil && !verifySecondFactor(currentUser, body.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kode 2FA salah"})
+		return
+	}
+
+	if currentUser.DeletionScheduledAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Akun sudah dijadwalkan untuk dihapus"})
+		return
+	}
+
+	scheduledAt := time.Now().Add(deletionGrace())
+	if err := initializers.DB.Model(&currentUser).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menjadwalkan penghapusan akun"})
+		return
+	}
+
+	// log out everywhere, the user can still login again and cancel (login alone doesn't cancel)
+	auth.RevokeAllForUser(currentUser.ID)
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":               "Akun akan dihapus. Untuk membatalkan, login kembali lalu batalkan penghapusan (POST /account/cancel-deletion) sebelum tanggal ini.",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+// CANCEL ACCOUNT DELETION
+
+func CancelAccountDeletion(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if currentUser.DeletionScheduledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak dijadwalkan untuk dihapus"})
+		return
+	}
+
+	if err := initializers.DB.Model(&currentUser).Update("deletion_scheduled_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membatalkan penghapusan akun"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Penghapusan akun dibatalkan"})
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// columns of models.CafeAmenities, for updates with Select
+var amenityColumns = []string{
+	"wifi_available", "wifi_speed_mbps", "power_outlets", "seating_capacity", "noise_level",
+	"pet_friendly", "motorcycle_parking", "car_parking", "prayer_room", "smoking_area",
+}
+
+// "amenities" object of CreateCafe / UpdateCafe, fields that are not sent stay as they are
+type amenitiesInput struct {
+	WifiAvailable     *bool    `json:"wifi_available"`
+	WifiSpeedMbps     *float64 `json:"wifi_speed_mbps"`
+	PowerOutlets      *string  `json:"power_outlets"`
+	SeatingCapacity   *int     `json:"seating_capacity"`
+	NoiseLevel        *string  `json:"noise_level"`
+	PetFriendly       *bool    `json:"pet_friendly"`
+	MotorcycleParking *bool    `json:"motorcycle_parking"`
+	CarParking        *bool    `json:"car_parking"`
+	PrayerRoom        *bool    `json:"prayer_room"`
+	SmokingArea       *bool    `json:"smoking_area"`
+}
+
+// copy the sent fields into a, returns an error message for the client
+func (in amenitiesInput) apply(a *models.CafeAmenities) string {
+	if in.WifiSpeedMbps != nil && *in.WifiSpeedMbps < 0 {
+		return "wifi_speed_mbps tidak boleh negatif"
+	}
+	if in.SeatingCapacity != nil && *in.SeatingCapacity < 0 {
+		return "seating_capacity tidak boleh negatif"
+	}
+	if in.PowerOutlets != nil && *in.PowerOutlets != "" && !slices.Contains(models.PowerOutletLevels, *in.PowerOutlets) {
+		return "power_outlets harus salah satu dari: " + strings.Join(models.PowerOutletLevels, ", ")
+	}
+	if in.NoiseLevel != nil && *in.NoiseLevel != "" && !slices.Contains(models.NoiseLevels, *in.NoiseLevel) {
+		return "noise_level harus salah satu dari: " + strings.Join(models.NoiseLevels, ", ")
+	}
+
+	if in.WifiAvailable != nil {
+		a.WifiAvailable = in.WifiAvailable
+	}
+	if in.WifiSpeedMbps != nil {
+		a.WifiSpeedMbps = in.WifiSpeedMbps
+		// a measured speed means there is wifi
+		wifi := true
+		a.WifiAvailable = &wifi
+	}
+	if in.PowerOutlets != nil {
+		a.PowerOutlets = *in.PowerOutlets
+	}
+	if in.SeatingCapacity != nil {
+		a.SeatingCapacity = in.SeatingCapacity
+	}
+	if in.NoiseLevel != nil {
+		a.NoiseLevel = *in.NoiseLevel
+	}
+	if in.PetFriendly != nil {
+		a.PetFriendly = in.PetFriendly
+	}
+	if in.MotorcycleParking != nil {
+		a.MotorcycleParking = in.MotorcycleParking
+	}
+	if in.CarParking != nil {
+		a.CarParking = in.CarParking
+	}
+	if in.PrayerRoom != nil {
+		a.PrayerRoom = in.PrayerRoom
+	}
+	if in.SmokingArea != nil {
+		a.SmokingArea = in.SmokingArea
+	}
+	return ""
+}
+
+// amenity filters of the cafe list, every filter is optional:
+//
+//	?wifi=true&min_wifi_speed=20&power_outlets=few,plenty&min_seating=30
+//	&noise_level=quiet,moderate&pet_friendly=true&parking=motorcycle,car
+//	&prayer_room=true&smoking_area=false
+//
+// cafes where the attribute is unknown never match
+func amenityFilters(c *gin.Context, db *gorm.DB) (*gorm.DB, string) {
+	for param, column := range map[string]string{
+		"wifi":         "wifi_available",
+		"pet_friendly": "pet_friendly",
+		"prayer_room":  "prayer_room",
+		"smoking_area": "smoking_area",
+	} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, param + " harus true atau false"
+		}
+		db = db.Where("cafes."+column+" = ?", b)
+	}
+
+	if v := c.Query("min_wifi_speed"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil || speed < 0 {
+			return nil, "min_wifi_speed harus angka (Mbps)"
+		}
+		db = db.Where("cafes.wifi_speed_mbps >= ?", speed)
+	}
+
+	if v := c.Query("min_seating"); v != "" {
+		seats, err := strconv.Atoi(v)
+		if err != nil || seats < 0 {
+			return nil, "min_seating harus angka"
+		}
+		db = db.Where("cafes.seating_capacity >= ?", seats)
+	}
+
+	for param, allowed := range map[string][]string{
+		"power_outlets": models.PowerOutletLevels,
+		"noise_level":   models.NoiseLevels,
+	} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		values := strings.Split(v, ",")
+		for _, value := range values {
+			if !slices.Contains(allowed, value) {
+				return nil, param + " harus salah satu dari: " + strings.Join(allowed, ", ")
+			}
+		}
+		db = db.Where("cafes."+param+" IN ?", values)
+	}
+
+	if v := c.Query("parking"); v != "" {
+		for _, kind := range strings.Split(v, ",") {
+			switch kind {
+			case "motorcycle":
+				db = db.Where("cafes.motorcycle_parking = ?", true)
+			case "car":
+				db = db.Where("cafes.car_parking = ?", true)
+			default:
+				return nil, "parking harus motorcycle dan/atau car"
+			}
+		}
+	}
+
+	return db, ""
+}
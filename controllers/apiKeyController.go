@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+// CREATE API KEY
+
+func CreateAPIKey(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var body struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"` // 0 = never expires
+	}
+
+	if c.ShouldBindJSON(&body) != nil || strings.TrimSpace(body.Name) == "" || len(body.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nama dan scopes wajib diisi"})
+		return
+	}
+
+	for _, scope := range body.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scope tidak valid: " + scope + " (read, write:cafes, write:ratings)"})
+			return
+		}
+	}
+
+	if body.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days tidak valid"})
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat API key"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		UserID:  currentUser.ID,
+		Name:    strings.TrimSpace(body.Name),
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  strings.Join(body.Scopes, ","),
+	}
+	if body.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, body.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := initializers.DB.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key berhasil dibuat! Simpan key ini, hanya ditampilkan sekali.",
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// GET API KEYS
+
+func GetAPIKeys(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var apiKeys []models.APIKey
+	result := initializers.DB.
+		Where("user_id = ? AND revoked_at IS NULL", currentUser.ID).
+		Order("created_at desc").
+		Find(&apiKeys)
+
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": apiKeys})
+}
+
+// REVOKE API KEY
+
+func DeleteAPIKey(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var apiKey models.APIKey
+	if err := initializers.DB.First(&apiKey, c.Param("id")).Error; err != nil || apiKey.UserID != currentUser.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key tidak ditemukan"})
+		return
+	}
+
+	if err := initializers.DB.Model(&apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencabut API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key berhasil dicabut"})
+}
@@ -1,15 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
-	"os"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/rizqy/cafetify/auth"
 	"github.com/rizqy/cafetify/initializers"
 	"github.com/rizqy/cafetify/models"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // ---------------------------
@@ -27,10 +27,17 @@ func Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca input"})
 		return
 	}
+	body.Email = normalizeEmail(body.Email)
+
+	// check the password policy (length, character classes, breached list)
+	if problems := auth.PasswordPolicyFromEnv().Check(body.Password); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password tidak memenuhi syarat", "details": problems})
+		return
+	}
 
 	// Hash Password
 	// For security, we must not store the plain password in the database.
-	hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), 10)
+	hash, err := auth.HashPassword(body.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal meng-hash password"})
 		return
@@ -40,18 +47,28 @@ func Register(c *gin.Context) {
 	user := models.User{
 		Username: body.Username,
 		Email:    body.Email,
-		Password: string(hash), // Save hash instead of plain password
+		Password: hash, // Save hash instead of plain password
 	}
 
 	result := initializers.DB.Create(&user)
 
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email sudah terdaftar"})
+		return
+	}
 	if result.Error != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membuat user (Email mungkin sudah ada)"})
 		return
 	}
 
+	// send the verification link, the account can still login without it
+	if err := sendVerificationEmail(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User dibuat, tapi gagal mengirim email verifikasi"})
+		return
+	}
+
 	// Send Success Response
-	c.JSON(http.StatusOK, gin.H{"message": "Registrasi berhasil! Silakan login."})
+	c.JSON(http.StatusOK, gin.H{"message": "Registrasi berhasil! Cek email untuk verifikasi, lalu silakan login."})
 }
 
 // ---------------------------
@@ -68,46 +85,95 @@ func Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca input"})
 		return
 	}
+	body.Email = normalizeEmail(body.Email)
+
+	// brute force protection: stop here if the account or IP is locked
+	accountKey, ipKey := loginLimitKeys(c, body.Email)
+	if wait := reserveLoginAttempt(accountKey, ipKey); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
 
 	// find user based on email
 	var user models.User
 	initializers.DB.First(&user, "email = ?", body.Email)
 
-	if user.ID == 0 {
+	// check password by comparing the hash
+	if user.ID == 0 || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)) != nil {
+		// unknown emails are counted too, so the lockout doesn't tell which email exists
+		recordLoginAudit(c, &user, body.Email, models.AuditLoginFailed, "wrong email or password")
+
+		if lock := loginFailed(accountKey, ipKey); lock > 0 {
+			recordLoginAudit(c, &user, body.Email, models.AuditLockout, "locked for "+lock.String())
+			tooManyAttempts(c, lock)
+			return
+		}
+
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Email atau password salah"})
 		return
 	}
 
-	// check password by comparing the hash
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email atau password salah"})
+	// password is correct, forget the failed attempts of this account
+	loginSucceeded(accountKey, ipKey)
+
+	// hash was made with an old BCRYPT_COST, we have the plain password now so upgrade it
+	if auth.NeedsRehash(user.Password) {
+		if newHash, err := auth.HashPassword(body.Password); err == nil {
+			initializers.DB.Model(&user).Update("password", newHash)
+		}
+	}
+
+	beginLogin(c, user)
+}
+
+// the first factor is done (password or social login)
+func beginLogin(c *gin.Context, user models.User) {
+	// 2FA is on -> don't give the token yet, the client must send the code to /login/mfa
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := auth.GenerateMFAToken(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Masukkan kode 2FA",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
 		return
 	}
 
-	// create JWT token (access)
-	// only available for 30 days
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID,                                    // Subject (ID User)
-		"exp": time.Now().Add(time.Hour * 24 * 30).Unix(), // expires in 30 days
-	})
+	completeLogin(c, user)
+}
 
-	// Sign the  token with our JWT_SECRET
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+// start a session and send the tokens, last step of every login
+func completeLogin(c *gin.Context, user models.User) {
+	// every login is a new session (device)
+	session, csrfToken, err := startSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat sesi"})
+		return
+	}
 
+	// create a short lived access token + refresh token
+	tokenString, refreshToken, err := issueTokens(c, session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
 		return
 	}
 
-	// send token in cookie
+	// cookie logins must send this token back in X-CSRF-Token for POST/PUT/DELETE
+	setCSRFCookie(c, csrfToken)
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("Authorization", tokenString, 3600*24*30, "", "", false, true)
+	recordLoginAudit(c, &user, user.Email, models.AuditLoginSuccess, "")
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login berhasil!",
-		"token":   tokenString, // send to body as well to test via Postman
+		"message":       "Login berhasil!",
+		"token":         tokenString, // send to body as well to test via Postman
+		"refresh_token": refreshToken,
+		"expires_in":    int(auth.AccessTokenTTL().Seconds()),
+		"csrf_token":    csrfToken,
 	})
 }
 
@@ -115,7 +181,7 @@ func Login(c *gin.Context) {
 func GetProfile(c *gin.Context) {
 	// get user that was set by middleware
 	user, _ := c.Get("user")
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	c.JSON(http.StatusOK, gin.H{"user": user.(models.User).Account()})
 }
 
 // update profile
@@ -134,15 +200,35 @@ func UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	// Update data di database
-	initializers.DB.Model(&currentUser).Updates(models.User{
-		Username: body.Username,
-		Email:    body.Email,
-	})
+	// Update data di database (username only, email needs confirmation)
+	if body.Username != "" && body.Username != currentUser.Username {
+		if err := initializers.DB.Model(&currentUser).Update("username", body.Username).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui profil"})
+			return
+		}
+	}
+
+	message := "Profil berhasil diperbarui"
+
+	// new email -> send confirmation link, the email is changed after confirm
+	newEmail := normalizeEmail(body.Email)
+	if newEmail != "" && newEmail != currentUser.Email {
+		err := startEmailChange(currentUser, newEmail)
+		if errors.Is(err, errEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email sudah dipakai akun lain"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses ganti email"})
+			return
+		}
+
+		message = "Profil berhasil diperbarui. Cek email baru kamu untuk konfirmasi ganti email."
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Profil berhasil diperbarui",
-		"user":    currentUser,
+		"message": message,
+		"user":    currentUser.Account(),
 	})
 }
 
@@ -169,15 +255,30 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	if problems := auth.PasswordPolicyFromEnv().Check(body.NewPassword); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password baru tidak memenuhi syarat", "details": problems})
+		return
+	}
+
 	// hash new password
-	newHash, err := bcrypt.GenerateFromPassword([]byte(body.NewPassword), 10)
+	newHash, err := auth.HashPassword(body.NewPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses password baru"})
 		return
 	}
 
 	// save the new pw to DB
-	initializers.DB.Model(&currentUser).Update("password", string(newHash))
+	if err := initializers.DB.Model(&currentUser).Update("password", newHash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan password baru"})
+		return
+	}
+
+	// old tokens must stop working after a password change
+	if err := auth.RevokeAllForUser(currentUser.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout dari semua sesi"})
+		return
+	}
+	clearAuthCookies(c)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password berhasil diubah!"})
+	c.JSON(http.StatusOK, gin.H{"message": "Password berhasil diubah! Silakan login kembali."})
 }
@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"encoding/json"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/jobs"
 	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
 )
 
 // 1. CREATE CAFE
@@ -15,8 +19,11 @@ import (
 func CreateCafe(c *gin.Context) {
 
 	var body struct {
-		Name      string  `json:"name" binding:"required"`
-		Address   string  `json:"address" binding:"required"`
+		Name      string          `json:"name" binding:"required"`
+		Address   string          `json:"address" binding:"required"`
+		Latitude  *float64        `json:"latitude"`
+		Longitude *float64        `json:"longitude"`
+		Amenities *amenitiesInput `json:"amenities"`
 
 		AmbienceRating int    `json:"ambience_rating" binding:"required"`
 		ServiceRating  int    `json:"service_rating" binding:"required"`
@@ -31,6 +38,11 @@ func CreateCafe(c *gin.Context) {
 		return
 	}
 
+	if !validLocation(body.Latitude, body.Longitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Latitude dan longitude harus diisi berdua dan dalam rentang yang valid"})
+		return
+	}
+
 	// get user ID (the one who added the new cafe)
 	user, exists := c.Get("user")
 	if !exists {
@@ -39,15 +51,26 @@ func CreateCafe(c *gin.Context) {
 	}
 	userID := user.(models.User).ID
 
-	// --- DATABASE TRANSACTION ---
-
-	tx := initializers.DB.Begin()
-
 	cafe := models.Cafe{
 		Name:      body.Name,
 		Address:   body.Address,
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
 		UserID:    userID, // Save the owner
 	}
+	if body.Latitude != nil {
+		cafe.LocationSource = models.LocationManual
+	}
+	if body.Amenities != nil {
+		if msg := body.Amenities.apply(&cafe.Amenities); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	// --- DATABASE TRANSACTION ---
+
+	tx := initializers.DB.Begin()
 
 	if err := tx.Create(&cafe).Error; err != nil {
 		tx.Rollback()
@@ -55,6 +78,13 @@ func CreateCafe(c *gin.Context) {
 		return
 	}
 
+	// coordinates + address parts are looked up in the background
+	if err := jobs.QueueGeocode(tx, &cafe); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan kafe"})
+		return
+	}
+
 	// save cafe rating
 	rating := models.PersonalRating{
 		UserID:         userID,
@@ -102,6 +132,8 @@ func CreateCafe(c *gin.Context) {
 	}
 
 	tx.Commit()
+	jobs.WakeGeocoder()
+	cafe.Stats = refreshRatingStats(cafe.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Kafe berhasil dibuat!",
@@ -159,6 +191,7 @@ func RateCafe(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan rating: " + result.Error.Error()})
 		return
 	}
+	stats := refreshRatingStats(personalRating.CafeID)
 
 	// process tag & update if any new tag appear
 	if body.TagsInput != "" {
@@ -184,17 +217,55 @@ func RateCafe(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Rating berhasil ditambahkan!",
 		"rating":  personalRating,
+		"stats":   stats,
 	})
 }
 
 // GET ALL CAFES
 
 func GetAllCafes(c *gin.Context) {
-	var cafes []models.Cafe
 	tagFilter := c.Query("tag")
 	searchQuery := c.Query("search")
 
-	db := initializers.DB.Preload("Ratings").Preload("Tags").Preload("User")
+	openAt, ok := parseOpenFilter(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "open_at harus RFC3339 (2026-10-18T19:30:00+07:00) atau 2026-10-18T19:30"})
+		return
+	}
+
+	limit, err := parsePageLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sortName, order, err := parseCafeSort(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	includes, err := parseCafeIncludes(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := parseCafeFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cursor *cafeCursor
+	if v := c.Query("cursor"); v != "" {
+		cur, err := decodeCafeCursor(v)
+		// a cursor only works with the sort order it was made for
+		if err != nil || cur.Sort != sortName {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor tidak valid"})
+			return
+		}
+		cursor = &cur
+	}
+
+	db := initializers.DB.Model(&models.Cafe{})
 
 	if tagFilter != "" && tagFilter != "Semua" {
 		// Filter by tag name using join
@@ -204,17 +275,160 @@ func GetAllCafes(c *gin.Context) {
 	}
 
 	if searchQuery != "" {
-		db = db.Where("name LIKE ?", "%"+searchQuery+"%")
+		db = db.Where("cafes.name LIKE ?", "%"+searchQuery+"%")
 	}
 
-	result := db.Find(&cafes)
+	db, msg := menuFilter(c, db)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	db, msg = amenityFilters(c, db)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+	db, msg = ratingStatsFilters(c, db)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"cafes": cafes})
+	// new session, so every page query below starts from the same filters
+	db = db.Session(&gorm.Session{})
+
+	// 1. ids of the page (+ sort key for the next cursor).
+	// without ?limit= and ?cursor= every cafe is returned, like before pagination existed
+	// (the dashboard still loads the whole list at once)
+	paginated := c.Query("limit") != "" || c.Query("cursor") != ""
+
+	var page []cafeKey
+	var next *cafeCursor
+	if !paginated {
+		if err := order.apply(db, sortName, nil).Scan(&page).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+		if openAt != nil {
+			if page, err = openCafeKeys(page, openAt); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+				return
+			}
+		}
+	}
+
+	// open_now / open_at is checked in Go (timezone of every cafe),
+	// so keep reading until the page is full, there are no more cafes or
+	// maxScanBatches were read (then the page is shorter and next continues after the last scanned cafe)
+	for batches := 1; paginated; batches++ {
+		var batch []cafeKey
+		if err := order.apply(db, sortName, cursor).Limit(limit + 1).Scan(&batch).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+
+		matched := batch
+		if openAt != nil {
+			if matched, err = openCafeKeys(batch, openAt); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+				return
+			}
+		}
+		page = append(page, matched...)
+
+		if len(page) > limit {
+			page = page[:limit]
+			last := page[limit-1].cursor(sortName)
+			next = &last
+			break
+		}
+		if len(batch) <= limit {
+			break
+		}
+		last := batch[len(batch)-1].cursor(sortName)
+		cursor = &last
+		if batches == maxScanBatches {
+			next = cursor
+			break
+		}
+	}
+	hasMore := next != nil
+
+	// 2. the cafes of the page with the relations that were asked for
+	ids := make([]uint, len(page))
+	for i, k := range page {
+		ids[i] = k.ID
+	}
+
+	load := initializers.DB
+	for _, include := range includes {
+		switch include {
+		case "ratings":
+			load = load.Preload("Ratings")
+		case "tags":
+			load = load.Preload("Tags")
+		case "user":
+			load = load.Preload("User")
+		case "photos":
+			load = load.Preload("Photos")
+		case "stats":
+			load = load.Preload("Stats")
+		case "hours":
+			load = preloadOpeningHours(load, openAt)
+		}
+	}
+
+	var cafes []models.Cafe
+	if len(ids) > 0 {
+		if err := load.Find(&cafes, ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+	}
+
+	// Find doesn't keep the order of the ids
+	position := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	sort.Slice(cafes, func(i, j int) bool { return position[cafes[i].ID] < position[cafes[j].ID] })
+
+	rows := make([]map[string]json.RawMessage, len(cafes))
+	for i, cafe := range cafes {
+		if rows[i], err = projectCafe(cafe, fields, includes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+	}
+	if center, ok, _ := queryPoint(c); ok {
+		addDistances(rows, cafes, center)
+	}
+
+	if !paginated {
+		c.JSON(http.StatusOK, gin.H{"cafes": rows})
+		return
+	}
+
+	// 3. pagination info, in the body and as Link header (RFC 8288)
+	nextCursor := ""
+	links := []string{`<` + pageURL(c, "") + `>; rel="first"`}
+	if hasMore {
+		nextCursor = next.encode()
+		links = append(links, `<`+pageURL(c, nextCursor)+`>; rel="next"`)
+	}
+	c.Header("Link", strings.Join(links, ", "))
+
+	c.JSON(http.StatusOK, gin.H{
+		"cafes": rows,
+		"pagination": gin.H{
+			"limit":       limit,
+			"sort":        sortName,
+			"has_more":    hasMore,
+			"next_cursor": nextCursor,
+		},
+	})
 }
 
 //  UPDATE CAFE
@@ -224,8 +438,11 @@ func UpdateCafe(c *gin.Context) {
 
 	// body struct with more fields (like CreateCafe)
 	var body struct {
-		Name      string  `json:"name"`
-		Address   string  `json:"address"`
+		Name      string          `json:"name"`
+		Address   string          `json:"address"`
+		Latitude  *float64        `json:"latitude"`
+		Longitude *float64        `json:"longitude"`
+		Amenities *amenitiesInput `json:"amenities"`
 
 		AmbienceRating int    `json:"ambience_rating"`
 		ServiceRating  int    `json:"service_rating"`
@@ -240,57 +457,106 @@ func UpdateCafe(c *gin.Context) {
 		return
 	}
 
+	if !validLocation(body.Latitude, body.Longitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Latitude dan longitude harus diisi berdua dan dalam rentang yang valid"})
+		return
+	}
+
 	var cafe models.Cafe
 	if result := initializers.DB.First(&cafe, cafeID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Kafe tidak ditemukan"})
 		return
 	}
 
-	// Check ownership
+	// Check ownership (moderators can edit any cafe)
 	user, _ := c.Get("user")
-	userID := user.(models.User).ID
+	currentUser := user.(models.User)
+	userID := currentUser.ID
+	isOwner := cafe.UserID == userID
 
-	if cafe.UserID != userID {
+	if !canModify(currentUser, cafe.UserID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk mengedit kafe ini"})
 		return
 	}
 
+	before := gin.H{"name": cafe.Name, "address": cafe.Address, "amenities": cafe.Amenities}
+
+	amenities := cafe.Amenities
+	if body.Amenities != nil {
+		if msg := body.Amenities.apply(&amenities); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
 	// --- DATABASE TRANSACTION ---
 	tx := initializers.DB.Begin()
 
 	// 1. Update Cafe Details (Safe Update using Map)
-	if err := tx.Model(&cafe).Updates(map[string]interface{}{
-		"Name":      body.Name,
-		"Address":   body.Address,
-	}).Error; err != nil {
+	updates := map[string]interface{}{
+		"Name":    body.Name,
+		"Address": body.Address,
+	}
+	// coordinates are optional, only overwrite them when sent
+	addressChanged := body.Address != "" && body.Address != cafe.Address
+	if body.Latitude != nil {
+		updates["Latitude"] = body.Latitude
+		updates["Longitude"] = body.Longitude
+		updates["LocationSource"] = models.LocationManual
+	} else if addressChanged {
+		// new address without coordinates -> the old ones are wrong, let the geocoder set them
+		updates["LocationSource"] = ""
+	}
+
+	if err := tx.Model(&cafe).Updates(updates).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal update kafe"})
 		return
 	}
 
+	if body.Amenities != nil {
+		if err := tx.Model(&cafe).Select(amenityColumns).Updates(models.Cafe{Amenities: amenities}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal update kafe"})
+			return
+		}
+		cafe.Amenities = amenities
+	}
+
+	if addressChanged {
+		if err := jobs.QueueGeocode(tx, &cafe); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal update kafe"})
+			return
+		}
+	}
+
 	// 2. Update Personal Rating (Find existing rating by this user for this cafe)
-	var rating models.PersonalRating
-	if err := tx.Where("user_id = ? AND cafe_id = ?", userID, cafe.ID).First(&rating).Error; err == nil {
-		// Rating exists, update it
-		tx.Model(&rating).Updates(map[string]interface{}{
-			"AmbienceRating": body.AmbienceRating,
-			"ServiceRating":  body.ServiceRating,
-			"PriceLevel":     body.PriceLevel,
-			"MenuVariety":    body.MenuVariety,
-			"Notes":          body.Notes,
-		})
-	} else {
-		// Rating doesn't exist (maybe legacy data), create it
-		newRating := models.PersonalRating{
-			UserID:         userID,
-			CafeID:         cafe.ID,
-			AmbienceRating: body.AmbienceRating,
-			ServiceRating:  body.ServiceRating,
-			PriceLevel:     body.PriceLevel,
-			MenuVariety:    body.MenuVariety,
-			Notes:          body.Notes,
+	// a moderator only fixes the cafe data, the owner's rating stays as it is
+	if isOwner {
+		var rating models.PersonalRating
+		if err := tx.Where("user_id = ? AND cafe_id = ?", userID, cafe.ID).First(&rating).Error; err == nil {
+			// Rating exists, update it
+			tx.Model(&rating).Updates(map[string]interface{}{
+				"AmbienceRating": body.AmbienceRating,
+				"ServiceRating":  body.ServiceRating,
+				"PriceLevel":     body.PriceLevel,
+				"MenuVariety":    body.MenuVariety,
+				"Notes":          body.Notes,
+			})
+		} else {
+			// Rating doesn't exist (maybe legacy data), create it
+			newRating := models.PersonalRating{
+				UserID:         userID,
+				CafeID:         cafe.ID,
+				AmbienceRating: body.AmbienceRating,
+				ServiceRating:  body.ServiceRating,
+				PriceLevel:     body.PriceLevel,
+				MenuVariety:    body.MenuVariety,
+				Notes:          body.Notes,
+			}
+			tx.Create(&newRating)
 		}
-		tx.Create(&newRating)
 	}
 
 	// 3. Update Tags
@@ -320,7 +586,25 @@ func UpdateCafe(c *gin.Context) {
 		}
 	}
 
+	if !isOwner {
+		if err := recordModeration(tx, currentUser, "cafe.update", "cafe", cafe.ID, cafe.UserID, gin.H{
+			"before":     before,
+			"after":      gin.H{"name": cafe.Name, "address": cafe.Address, "amenities": cafe.Amenities},
+			"tags_input": body.TagsInput,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+			return
+		}
+	}
+
 	tx.Commit()
+	if addressChanged {
+		jobs.WakeGeocoder()
+	}
+	if isOwner {
+		cafe.Stats = refreshRatingStats(cafe.ID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Kafe berhasil diperbarui!", "cafe": cafe})
 }
@@ -338,19 +622,48 @@ func DeleteCafe(c *gin.Context) {
 	}
 
 	user, _ := c.Get("user")
-	userID := user.(models.User).ID
+	currentUser := user.(models.User)
 
-	if cafe.UserID != userID {
+	if !canModify(currentUser, cafe.UserID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk menghapus kafe ini"})
 		return
 	}
 
-	result := initializers.DB.Delete(&models.Cafe{}, cafeID)
+	tx := initializers.DB.Begin()
 
-	if result.Error != nil {
+	if err := tx.Delete(&models.Cafe{}, cafe.ID).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus kafe"})
 		return
 	}
 
+	// a deleted cafe isn't listed anymore, its stats and photos go with it
+	if err := tx.Delete(&models.CafeRatingStats{}, cafe.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus kafe"})
+		return
+	}
+	photoKeys, err := deletePhotoRows(tx, "cafe_id = ?", cafe.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus kafe"})
+		return
+	}
+
+	// moderator deleted someone else's cafe
+	if cafe.UserID != currentUser.ID {
+		if err := recordModeration(tx, currentUser, "cafe.delete", "cafe", cafe.ID, cafe.UserID, gin.H{
+			"name":    cafe.Name,
+			"address": cafe.Address,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+			return
+		}
+	}
+
+	tx.Commit()
+	deletePhotoFiles(photoKeys...)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Kafe berhasil dihapus"})
 }
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/geo"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+
+	// with open_now / open_at one request reads at most this many batches of limit+1 cafes
+	maxScanBatches = 10
+)
+
+// the heavy relations, ?include= turns them on / off.
+// without include the list looks like before pagination existed (all of them except hours) + stats
+var (
+	cafeIncludes        = []string{"ratings", "tags", "user", "photos", "hours", "stats"}
+	defaultCafeIncludes = []string{"ratings", "tags", "user", "photos", "stats"}
+)
+
+// json keys of the relations, they are controlled by include and not by fields
+var cafeRelationKeys = map[string]string{
+	"ratings":            "ratings",
+	"tags":               "tags",
+	"user":               "user",
+	"photos":             "photos",
+	"opening_hours":      "hours",
+	"opening_exceptions": "hours",
+	"menu_items":         "",
+	"stats":              "stats",
+}
+
+// a sort order of the list. expr is the SQL of the sort key ("" = only the id),
+// cafes.id is always the last key so the order is unique
+type cafeSort struct {
+	expr    string
+	args    []interface{}
+	numeric bool
+	desc    bool
+}
+
+// where the previous page stopped
+type cafeCursor struct {
+	Sort string  `json:"s"`
+	ID   uint    `json:"id"`
+	Str  string  `json:"v,omitempty"`
+	Num  float64 `json:"n,omitempty"`
+}
+
+func (cur cafeCursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCafeCursor(s string) (cafeCursor, error) {
+	var cur cafeCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(data, &cur)
+	return cur, err
+}
+
+// one row of the first query: only the id and the sort key
+type cafeKey struct {
+	ID      uint
+	SortStr sql.NullString
+	SortNum sql.NullFloat64
+}
+
+func (k cafeKey) cursor(sortName string) cafeCursor {
+	return cafeCursor{Sort: sortName, ID: k.ID, Str: k.SortStr.String, Num: k.SortNum.Float64}
+}
+
+// ?sort=newest|name|rating|rating_count|distance (distance needs ?lat=&lng=)
+func parseCafeSort(c *gin.Context) (string, cafeSort, error) {
+	name := c.DefaultQuery("sort", "newest")
+
+	switch name {
+	case "newest":
+		// ids only go up, so the newest cafe has the highest id
+		return name, cafeSort{desc: true}, nil
+	case "name":
+		return name, cafeSort{expr: "cafes.name"}, nil
+	case "rating":
+		return name, cafeSort{expr: "COALESCE((rs.ambience_mean + rs.service_mean) / 2, 0)", numeric: true, desc: true}, nil
+	case "rating_count":
+		return name, cafeSort{expr: "COALESCE(rs.rating_count, 0)", numeric: true, desc: true}, nil
+	case "distance":
+		center, ok, err := queryPoint(c)
+		if err != nil || !ok {
+			return name, cafeSort{}, errors.New("sort=distance butuh lat & lng")
+		}
+		return name, cafeSort{expr: distanceSQL, args: distanceArgs(center), numeric: true}, nil
+	}
+
+	return name, cafeSort{}, errors.New("sort harus newest, name, rating, rating_count atau distance")
+}
+
+// add the sort key, the keyset condition of the cursor and the ORDER BY
+func (s cafeSort) apply(db *gorm.DB, sortName string, cursor *cafeCursor) *gorm.DB {
+	switch sortName {
+	case "rating", "rating_count":
+		// the means are DOUBLE columns, so the cursor value compares exactly
+		db = db.Joins("LEFT JOIN cafe_rating_stats rs ON rs.cafe_id = cafes.id")
+	case "distance":
+		db = db.Where("cafes.latitude IS NOT NULL AND cafes.longitude IS NOT NULL")
+	}
+
+	op, dir := ">", "ASC"
+	if s.desc {
+		op, dir = "<", "DESC"
+	}
+
+	if s.expr == "" {
+		db = db.Select("cafes.id")
+		if cursor != nil {
+			db = db.Where("cafes.id "+op+" ?", cursor.ID)
+		}
+		return db.Order("cafes.id " + dir)
+	}
+
+	alias := "sort_str"
+	var last interface{}
+	if cursor != nil {
+		last = cursor.Str
+	}
+	if s.numeric {
+		alias = "sort_num"
+		if cursor != nil {
+			last = cursor.Num
+		}
+	}
+
+	db = db.Select("cafes.id, "+s.expr+" AS "+alias, s.args...)
+
+	if cursor != nil {
+		args := append(append([]interface{}{}, s.args...), last)
+		args = append(append(args, s.args...), last, cursor.ID)
+		db = db.Where(fmt.Sprintf("(%s %s ?) OR (%s = ? AND cafes.id %s ?)", s.expr, op, s.expr, op), args...)
+	}
+
+	return db.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  s.expr + " " + dir + ", cafes.id " + dir,
+		Vars: s.args,
+	}})
+}
+
+// ?include=ratings,tags ("include=" alone = no relations)
+func parseCafeIncludes(c *gin.Context) ([]string, error) {
+	v, ok := c.GetQuery("include")
+	if !ok {
+		return defaultCafeIncludes, nil
+	}
+
+	var includes []string
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(cafeIncludes, name) {
+			return nil, errors.New("include hanya boleh: " + strings.Join(cafeIncludes, ", "))
+		}
+		includes = append(includes, name)
+	}
+	return includes, nil
+}
+
+// ?fields=name,address,amenities -> only these keys (+ ID) in every cafe, nil = all
+func parseCafeFields(c *gin.Context) (map[string]bool, error) {
+	v := c.Query("fields")
+	if v == "" {
+		return nil, nil
+	}
+
+	known := map[string]json.RawMessage{}
+	data, _ := json.Marshal(models.Cafe{})
+	json.Unmarshal(data, &known)
+
+	fields := map[string]bool{"ID": true}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		_, isRelation := cafeRelationKeys[name]
+		if _, ok := known[name]; !ok || isRelation {
+			return nil, errors.New("field tidak dikenal: " + name)
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// the cafe as a json object with only the asked keys
+func projectCafe(cafe models.Cafe, fields map[string]bool, includes []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(cafe)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	for key := range out {
+		if include, isRelation := cafeRelationKeys[key]; isRelation {
+			if include == "" || !slices.Contains(includes, include) {
+				delete(out, key)
+			}
+			continue
+		}
+		if fields != nil && !fields[key] {
+			delete(out, key)
+		}
+	}
+	return out, nil
+}
+
+// the same list url with another cursor, for the Link header
+func pageURL(c *gin.Context, cursor string) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Del("cursor")
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// parse ?limit=, default 50 (when only ?cursor= is sent), max 100
+func parsePageLimit(c *gin.Context) (int, error) {
+	v := c.Query("limit")
+	if v == "" {
+		return defaultPageLimit, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, errors.New("limit tidak valid")
+	}
+	return min(n, maxPageLimit), nil
+}
+
+// distance of every cafe from ?lat=&lng=, for sort=distance
+func addDistances(rows []map[string]json.RawMessage, cafes []models.Cafe, center geo.Point) {
+	for i, cafe := range cafes {
+		if p, ok := cafe.Location(); ok {
+			d, _ := json.Marshal(geo.Distance(center, p))
+			rows[i]["distance_m"] = d
+		}
+	}
+}
+
+// ids of the batch whose cafe is open at the time of the filter
+func openCafeKeys(keys []cafeKey, filter *openFilter) ([]cafeKey, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(keys))
+	for i, k := range keys {
+		ids[i] = k.ID
+	}
+
+	var cafes []models.Cafe
+	if err := preloadOpeningHours(initializers.DB.Select("id", "timezone"), filter).Find(&cafes, ids).Error; err != nil {
+		return nil, err
+	}
+
+	open := map[uint]bool{}
+	for _, cafe := range cafes {
+		open[cafe.ID] = filter.match(cafe)
+	}
+
+	var result []cafeKey
+	for _, k := range keys {
+		if open[k.ID] {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
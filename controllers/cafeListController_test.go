@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"database/sql"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/models"
+)
+
+func TestCafeCursorRoundTrip(t *testing.T) {
+	tests := []cafeCursor{
+		{Sort: "newest", ID: 42},
+		{Sort: "name", ID: 7, Str: "Kopi \"Kenangan\" / Senja, 100%"},
+		{Sort: "rating", ID: 3, Num: 4.333333333333333},
+		{Sort: "rating", ID: 9, Num: math.Nextafter(4.5, 5)}, // must not lose the last bit
+		{Sort: "distance", ID: 1, Num: 0},
+	}
+
+	for _, cur := range tests {
+		encoded := cur.encode()
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("cursor %q is not url safe", encoded)
+		}
+		got, err := decodeCafeCursor(encoded)
+		if err != nil || got != cur {
+			t.Errorf("round trip of %+v = %+v, %v", cur, got, err)
+		}
+	}
+
+	for _, bad := range []string{"", "not base64!", "bm90IGpzb24"} {
+		if _, err := decodeCafeCursor(bad); err == nil {
+			t.Errorf("decodeCafeCursor(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCafeKeyCursor(t *testing.T) {
+	key := cafeKey{ID: 5, SortStr: sql.NullString{String: "Anomali", Valid: true}}
+	if got := key.cursor("name"); got != (cafeCursor{Sort: "name", ID: 5, Str: "Anomali"}) {
+		t.Errorf("name cursor = %+v", got)
+	}
+
+	key = cafeKey{ID: 6, SortNum: sql.NullFloat64{Float64: 3.75, Valid: true}}
+	if got := key.cursor("rating"); got != (cafeCursor{Sort: "rating", ID: 6, Num: 3.75}) {
+		t.Errorf("rating cursor = %+v", got)
+	}
+}
+
+// the SQL of one page, built without a database
+func pageSQL(t *testing.T, query string, cursor *cafeCursor) (string, []interface{}) {
+	t.Helper()
+
+	db := dryRunDB(t)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/cafes?"+query, nil)
+	name, order, err := parseCafeSort(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []cafeKey
+	stmt := order.apply(db.Model(&models.Cafe{}), name, cursor).Limit(11).Scan(&keys).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestCafeSortApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		cursor   *cafeCursor
+		contains []string
+		vars     []interface{}
+	}{
+		{
+			name:     "newest first page",
+			query:    "sort=newest",
+			contains: []string{"SELECT cafes.id FROM", "ORDER BY cafes.id DESC"},
+		},
+		{
+			name:     "newest next page",
+			query:    "sort=newest",
+			cursor:   &cafeCursor{Sort: "newest", ID: 20},
+			contains: []string{"cafes.id < ?", "ORDER BY cafes.id DESC"},
+			vars:     []interface{}{uint(20)},
+		},
+		{
+			// same name as the cursor -> the id decides, so a tie is never skipped or repeated
+			name:     "name ties",
+			query:    "sort=name",
+			cursor:   &cafeCursor{Sort: "name", ID: 4, Str: "Kopi"},
+			contains: []string{"(cafes.name > ?) OR (cafes.name = ? AND cafes.id > ?)", "ORDER BY cafes.name ASC, cafes.id ASC"},
+			vars:     []interface{}{"Kopi", "Kopi", uint(4)},
+		},
+		{
+			name:   "rating uses the stored stats",
+			query:  "sort=rating",
+			cursor: &cafeCursor{Sort: "rating", ID: 8, Num: 4.25},
+			contains: []string{
+				"LEFT JOIN cafe_rating_stats rs ON rs.cafe_id = cafes.id",
+				"COALESCE((rs.ambience_mean + rs.service_mean) / 2, 0) < ?",
+				"cafes.id < ?",
+				"DESC, cafes.id DESC",
+			},
+			vars: []interface{}{4.25, 4.25, uint(8)},
+		},
+		{
+			// the center is a bind variable in the select, the condition and the order
+			name:     "distance",
+			query:    "sort=distance&lat=-6.9&lng=107.6",
+			cursor:   &cafeCursor{Sort: "distance", ID: 2, Num: 150.5},
+			contains: []string{"cafes.latitude IS NOT NULL", "AS sort_num", "ASC, cafes.id ASC"},
+			vars: []interface{}{
+				-6.9, -6.9, 107.6, // select
+				-6.9, -6.9, 107.6, 150.5, -6.9, -6.9, 107.6, 150.5, uint(2), // where
+				-6.9, -6.9, 107.6, // order
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vars := pageSQL(t, tt.query, tt.cursor)
+			for _, part := range tt.contains {
+				if !strings.Contains(sql, part) {
+					t.Errorf("missing %q in\n%s", part, sql)
+				}
+			}
+			if tt.vars != nil {
+				// the last var is the LIMIT
+				got := vars[:len(vars)-1]
+				if len(got) != len(tt.vars) {
+					t.Fatalf("vars = %v, want %v", got, tt.vars)
+				}
+				for i := range got {
+					if got[i] != tt.vars[i] {
+						t.Errorf("vars = %v, want %v", got, tt.vars)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseCafeSortErrors(t *testing.T) {
+	for _, query := range []string{"sort=random", "sort=distance", "sort=distance&lat=100&lng=0"} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/cafes?"+query, nil)
+		if _, _, err := parseCafeSort(c); err == nil {
+			t.Errorf("parseCafeSort(%q) should fail", query)
+		}
+	}
+}
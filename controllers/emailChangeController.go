@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/mailer"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// the new address has 1 day to confirm
+	emailChangeTTL = 24 * time.Hour
+
+	// the old address can undo the change for 7 days (account takeover protection)
+	emailUndoTTL = 7 * 24 * time.Hour
+)
+
+var errEmailTaken = errors.New("email already used")
+
+// true if another account already uses the email
+func emailTaken(email string, exceptUserID uint) bool {
+	var count int64
+	initializers.DB.Model(&models.User{}).Where("email = ? AND id <> ?", email, exceptUserID).Count(&count)
+	return count > 0
+}
+
+// create the change request and send both emails
+func startEmailChange(user models.User, newEmail string) error {
+	if emailTaken(newEmail, user.ID) {
+		return errEmailTaken
+	}
+
+	confirmToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	undoToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// only the newest request can be confirmed
+	initializers.DB.Model(&models.EmailChangeRequest{}).
+		Where("user_id = ? AND confirmed_at IS NULL AND canceled_at IS NULL", user.ID).
+		Update("canceled_at", now)
+
+	request := models.EmailChangeRequest{
+		UserID:           user.ID,
+		OldEmail:         user.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: auth.HashToken(confirmToken),
+		UndoTokenHash:    auth.HashToken(undoToken),
+		ExpiresAt:        now.Add(emailChangeTTL),
+		UndoExpiresAt:    now.Add(emailUndoTTL),
+	}
+	if err := initializers.DB.Create(&request).Error; err != nil {
+		return err
+	}
+
+	sendMail(mailer.Message{
+		To:      newEmail,
+		Subject: "Konfirmasi email baru Cafetify",
+		Body: fmt.Sprintf("Halo %s,\n\nKlik link berikut untuk memakai email ini di akun Cafetify kamu (berlaku 24 jam):\n%s",
+			user.Username, frontendURL("/confirm-email-change", confirmToken)),
+	})
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Permintaan ganti email Cafetify",
+		Body: fmt.Sprintf("Halo %s,\n\nAda permintaan untuk mengganti email akun kamu menjadi %s.\nJika ini bukan kamu, klik link berikut untuk membatalkan (berlaku 7 hari):\n%s",
+			user.Username, newEmail, frontendURL("/undo-email-change", undoToken)),
+	})
+
+	return nil
+}
+
+// ---------------------------
+// CONFIRM EMAIL CHANGE (link in the email to the new address)
+// ---------------------------
+func ConfirmEmailChange(c *gin.Context) {
+	var request models.EmailChangeRequest
+	err := initializers.DB.First(&request, "confirm_token_hash = ?", auth.HashToken(c.Query("token"))).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengganti email"})
+		return
+	}
+	if err != nil || request.ConfirmedAt != nil || request.CanceledAt != nil || time.Now().After(request.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link konfirmasi tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	now := time.Now()
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.EmailChangeRequest{}).
+			Where("id = ? AND confirmed_at IS NULL AND canceled_at IS NULL", request.ID).
+			Update("confirmed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// the user must still have the email the request was made for
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", request.UserID, request.OldEmail).
+			Updates(map[string]interface{}{
+				"email":             request.NewEmail,
+				"email_verified_at": now, // clicking the link proves the new address
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email sudah dipakai akun lain"})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link konfirmasi tidak valid atau sudah kadaluarsa"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengganti email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email berhasil diganti!"})
+}
+
+var errEmailChangedAgain = errors.New("email changed again")
+
+// ---------------------------
+// UNDO EMAIL CHANGE (link in the notice to the old address)
+// ---------------------------
+// before confirm: the request is canceled.
+// after confirm: the old email is restored and every session is logged out,
+// because someone else may have changed it
+func UndoEmailChange(c *gin.Context) {
+	var request models.EmailChangeRequest
+	err := initializers.DB.First(&request, "undo_token_hash = ?", auth.HashToken(c.Query("token"))).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membatalkan ganti email"})
+		return
+	}
+	if err != nil || request.CanceledAt != nil || time.Now().After(request.UndoExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link pembatalan tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	now := time.Now()
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.EmailChangeRequest{}).
+			Where("id = ? AND canceled_at IS NULL", request.ID).
+			Update("canceled_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if request.ConfirmedAt == nil {
+			return nil
+		}
+
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", request.UserID, request.NewEmail).
+			Update("email", request.OldEmail)
+		if result.Error != nil {
+			return result.Error
+		}
+		// the email was changed again after this request, there is nothing to restore
+		if result.RowsAffected == 0 {
+			return errEmailChangedAgain
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errEmailChangedAgain):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email akun sudah diganti lagi, email lama tidak bisa dikembalikan"})
+		return
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email lama sudah dipakai akun lain"})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link pembatalan tidak valid atau sudah kadaluarsa"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membatalkan ganti email"})
+		return
+	}
+
+	if request.ConfirmedAt != nil {
+		auth.RevokeAllForUser(request.UserID)
+		c.JSON(http.StatusOK, gin.H{"message": "Email dikembalikan ke alamat lama. Semua sesi sudah logout, segera ganti password kamu."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Permintaan ganti email dibatalkan"})
+}
+
+// emails are saved and compared in lower case, so "Budi@Mail.com" can't
+// register a second account next to "budi@mail.com"
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := map[string]string{
+		"budi@mail.com":      "budi@mail.com",
+		"  Budi@Mail.COM \n": "budi@mail.com",
+		"BUDI.SANTOSO@X.ID":  "budi.santoso@x.id",
+		"":                   "",
+	}
+
+	for in, want := range tests {
+		if got := normalizeEmail(in); got != want {
+			t.Errorf("normalizeEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	// the lockout counts the same account however the email is typed
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/login", nil)
+	a, _ := loginLimitKeys(c, "Budi@Mail.com ")
+	b, _ := loginLimitKeys(c, "budi@mail.com")
+	if a != b {
+		t.Errorf("limit keys differ: %q, %q", a, b)
+	}
+}
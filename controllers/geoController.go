@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/geo"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNearbyRadius = 1000  // meters, around 12 minutes walking
+	maxNearbyRadius     = 50000 // meters
+	defaultNearbyLimit  = 50
+	maxNearbyLimit      = 200
+)
+
+// geo.Distance in SQL: haversine distance in meters of cafes.latitude/longitude
+// from the point in distanceArgs
+const distanceSQL = "2 * 6371000 * ASIN(LEAST(1, SQRT(POW(SIN(RADIANS(cafes.latitude - ?) / 2), 2) + " +
+	"COS(RADIANS(?)) * COS(RADIANS(cafes.latitude)) * POW(SIN(RADIANS(cafes.longitude - ?) / 2), 2))))"
+
+func distanceArgs(p geo.Point) []interface{} {
+	return []interface{}{p.Lat, p.Lat, p.Lng}
+}
+
+// cafe + distance from the point in the query (meters)
+type cafeWithDistance struct {
+	models.Cafe
+	Distance *float64 `json:"distance_m,omitempty"`
+}
+
+// latitude and longitude are optional, but must be sent together
+func validLocation(lat, lng *float64) bool {
+	if lat == nil && lng == nil {
+		return true
+	}
+	if lat == nil || lng == nil {
+		return false
+	}
+	return geo.Point{Lat: *lat, Lng: *lng}.Valid()
+}
+
+// read ?lat=&lng=, ok is false when they are not in the query
+func queryPoint(c *gin.Context) (geo.Point, bool, error) {
+	latStr, lngStr := c.Query("lat"), c.Query("lng")
+	if latStr == "" && lngStr == "" {
+		return geo.Point{}, false, nil
+	}
+
+	lat, err1 := strconv.ParseFloat(latStr, 64)
+	lng, err2 := strconv.ParseFloat(lngStr, 64)
+	p := geo.Point{Lat: lat, Lng: lng}
+	if err1 != nil || err2 != nil || !p.Valid() {
+		return geo.Point{}, false, errors.New("invalid lat / lng")
+	}
+	return p, true, nil
+}
+
+// GET NEARBY CAFES
+// ?lat=&lng=&radius=  -> cafes inside the radius (meters), nearest first
+// ?bbox=minLng,minLat,maxLng,maxLat -> cafes inside the map viewport
+// both can be combined: bbox filters, lat/lng adds the distance and the sort order
+
+func GetNearbyCafes(c *gin.Context) {
+	center, hasCenter, err := queryPoint(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat / lng tidak valid"})
+		return
+	}
+
+	limit := defaultNearbyLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit tidak valid"})
+			return
+		}
+		limit = min(n, maxNearbyLimit)
+	}
+
+	var box geo.BBox
+	radius := 0.0
+
+	if v := c.Query("bbox"); v != "" {
+		box, err = geo.ParseBBox(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bbox tidak valid: " + err.Error()})
+			return
+		}
+	} else if hasCenter {
+		radius = defaultNearbyRadius
+		if v := c.Query("radius"); v != "" {
+			radius, err = strconv.ParseFloat(v, 64)
+			if err != nil || radius <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "radius tidak valid"})
+				return
+			}
+			radius = min(radius, maxNearbyRadius)
+		}
+		box = geo.Around(center, radius)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Isi lat & lng, atau bbox"})
+		return
+	}
+
+	var hits []nearbyHit
+	if err := nearbyQuery(initializers.DB, box, center, hasCenter, radius, limit).Scan(&hits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	var cafes []models.Cafe
+	if len(ids) > 0 {
+		err := initializers.DB.Preload("Tags").Preload("User").Preload("Photos").Preload("Stats").
+			Where("id IN ?", ids).Find(&cafes).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+	}
+
+	byID := make(map[uint]models.Cafe, len(cafes))
+	for _, cafe := range cafes {
+		byID[cafe.ID] = cafe
+	}
+
+	// same order as the hits (nearest first)
+	results := make([]cafeWithDistance, 0, len(hits))
+	for _, hit := range hits {
+		cafe, ok := byID[hit.ID]
+		if !ok {
+			continue // deleted between the two queries
+		}
+		result := cafeWithDistance{Cafe: cafe}
+		if hasCenter {
+			result.Distance = &hit.Distance
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cafes": results})
+}
+
+// id + distance of one cafe found by nearbyQuery
+type nearbyHit struct {
+	ID       uint
+	Distance float64
+}
+
+// the ids of the nearby cafes, the cafes themselves are loaded afterwards with their preloads.
+// the box uses the location index, the distance is computed by the database so
+// only the nearest `limit` rows are sent back
+func nearbyQuery(db *gorm.DB, box geo.BBox, center geo.Point, hasCenter bool, radius float64, limit int) *gorm.DB {
+	db = db.Model(&models.Cafe{}).
+		Where("cafes.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat).
+		Where("cafes.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+
+	// without a center there is nothing to sort by
+	if !hasCenter {
+		return db.Select("cafes.id").Order("cafes.id").Limit(limit)
+	}
+
+	db = db.Select("cafes.id, "+distanceSQL+" AS distance", distanceArgs(center)...)
+
+	// the corners of the box are further away than the radius
+	if radius > 0 {
+		db = db.Having("distance <= ?", radius)
+	}
+
+	return db.Order("distance, cafes.id").Limit(limit)
+}
+
+// zoom levels where markers are still close enough to need clustering,
+// from this zoom on every cafe is its own feature
+const (
+	clusterMaxZoom = 17
+	clusterCellPx  = 60
+)
+
+// GET CAFES AS GEOJSON
+// ?tag=&bbox=minLng,minLat,maxLng,maxLat&zoom=
+// with zoom, cafes close to each other on the map are merged into one
+// cluster feature (properties.cluster = true) so the map gets a few hundred
+// features instead of every cafe. cafes without coordinates are left out
+
+func GetCafesGeoJSON(c *gin.Context) {
+	db := initializers.DB.Model(&models.Cafe{}).
+		Select("cafes.id, cafes.name, cafes.address, cafes.city, cafes.latitude, cafes.longitude").
+		Preload("Tags").
+		Preload("Stats").
+		Where("cafes.latitude IS NOT NULL AND cafes.longitude IS NOT NULL")
+
+	if tagFilter := c.Query("tag"); tagFilter != "" && tagFilter != "Semua" {
+		db = db.Joins("JOIN cafe_tags ON cafe_tags.cafe_id = cafes.id").
+			Joins("JOIN tags ON tags.id = cafe_tags.tag_id").
+			Where("tags.name = ?", tagFilter)
+	}
+
+	if v := c.Query("bbox"); v != "" {
+		box, err := geo.ParseBBox(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bbox tidak valid: " + err.Error()})
+			return
+		}
+		db = db.Where("cafes.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat).
+			Where("cafes.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	}
+
+	zoom := -1
+	if v := c.Query("zoom"); v != "" {
+		z, err := strconv.Atoi(v)
+		if err != nil || z < 0 || z > geo.MaxZoom {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "zoom harus angka 0 - 22"})
+			return
+		}
+		zoom = z
+	}
+
+	var cafes []models.Cafe
+	if err := db.Order("cafes.id").Find(&cafes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+		return
+	}
+
+	points := make([]geo.Point, len(cafes))
+	for i, cafe := range cafes {
+		points[i], _ = cafe.Location()
+	}
+
+	features := make([]geo.Feature, 0, len(cafes))
+
+	// no zoom (or zoomed in far enough) -> one feature per cafe
+	if zoom < 0 || zoom > clusterMaxZoom {
+		for i, cafe := range cafes {
+			features = append(features, cafeFeature(cafe, points[i]))
+		}
+	} else {
+		for _, cluster := range geo.ClusterByGrid(points, zoom, clusterCellPx) {
+			if len(cluster.Members) == 1 {
+				i := cluster.Members[0]
+				features = append(features, cafeFeature(cafes[i], points[i]))
+				continue
+			}
+
+			cafeIDs := make([]uint, len(cluster.Members))
+			for n, i := range cluster.Members {
+				cafeIDs[n] = cafes[i].ID
+			}
+
+			feature := geo.NewPointFeature(nil, cluster.Center, map[string]interface{}{
+				"cluster":     true,
+				"point_count": len(cluster.Members),
+				"cafe_ids":    cafeIDs,
+			})
+			// the client can zoom the map to this box when the cluster is clicked
+			feature.BBox = cluster.Bounds.GeoJSON()
+			features = append(features, feature)
+		}
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, geo.NewFeatureCollection(features))
+}
+
+func cafeFeature(cafe models.Cafe, p geo.Point) geo.Feature {
+	tags := make([]string, len(cafe.Tags))
+	for i, tag := range cafe.Tags {
+		tags[i] = tag.Name
+	}
+
+	// no stats row yet = no ratings
+	var stats models.CafeRatingStats
+	if cafe.Stats != nil {
+		stats = *cafe.Stats
+	}
+
+	return geo.NewPointFeature(cafe.ID, p, map[string]interface{}{
+		"name":         cafe.Name,
+		"address":      cafe.Address,
+		"city":         cafe.City,
+		"tags":         tags,
+		"avg_ambience": stats.AmbienceMean,
+		"avg_service":  stats.ServiceMean,
+		"rating_count": stats.RatingCount,
+	})
+}
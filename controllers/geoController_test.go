@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqy/cafetify/geo"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// gorm on mysql that only builds the SQL, no database needed
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "test:test@tcp(127.0.0.1:1)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestNearbyQuery(t *testing.T) {
+	center := geo.Point{Lat: -6.9147, Lng: 107.6098}
+	box := geo.Around(center, 1000)
+
+	tests := []struct {
+		name      string
+		hasCenter bool
+		radius    float64
+		want      []string
+		notWant   []string
+		wantVars  []interface{}
+	}{
+		{
+			name:      "radius",
+			hasCenter: true,
+			radius:    1000,
+			want: []string{
+				"SELECT cafes.id, 2 * 6371000 * ASIN(",
+				"AS distance FROM `cafes`",
+				"cafes.latitude BETWEEN ? AND ?",
+				"HAVING distance <= ?",
+				"ORDER BY distance, cafes.id LIMIT ?",
+			},
+			wantVars: []interface{}{center.Lat, center.Lat, center.Lng, box.MinLat, box.MaxLat, box.MinLng, box.MaxLng, 1000.0, 20},
+		},
+		{
+			name:      "bbox with a center",
+			hasCenter: true,
+			want:      []string{"AS distance", "ORDER BY distance, cafes.id LIMIT ?"},
+			notWant:   []string{"HAVING"},
+		},
+		{
+			name:    "bbox only",
+			want:    []string{"SELECT cafes.id FROM `cafes`", "ORDER BY cafes.id LIMIT ?"},
+			notWant: []string{"distance", "HAVING"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits []nearbyHit
+			stmt := nearbyQuery(dryRunDB(t), box, center, tt.hasCenter, tt.radius, 20).Scan(&hits).Statement
+			sql := stmt.SQL.String()
+
+			for _, part := range append(tt.want, "`cafes`.`deleted_at` IS NULL") {
+				if !strings.Contains(sql, part) {
+					t.Errorf("SQL misses %q:\n%s", part, sql)
+				}
+			}
+			for _, part := range tt.notWant {
+				if strings.Contains(sql, part) {
+					t.Errorf("SQL contains %q:\n%s", part, sql)
+				}
+			}
+			if tt.wantVars != nil {
+				if len(stmt.Vars) != len(tt.wantVars) {
+					t.Fatalf("vars = %v, want %v", stmt.Vars, tt.wantVars)
+				}
+				for i := range tt.wantVars {
+					if stmt.Vars[i] != tt.wantVars[i] {
+						t.Errorf("var %d = %v, want %v", i, stmt.Vars[i], tt.wantVars[i])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+// limiter keys of one login attempt
+func loginLimitKeys(c *gin.Context, email string) (string, string) {
+	return "account:" + normalizeEmail(email), "ip:" + c.ClientIP()
+}
+
+// count one attempt on the account and the IP before the password / code is checked,
+// in one step with the lock check so parallel requests can't all slip through.
+// returns how long the account or the IP is still locked (0 = counted, go on)
+func reserveLoginAttempt(accountKey, ipKey string) time.Duration {
+	accountWait, err := initializers.AccountLimiter.Reserve(accountKey)
+	if err != nil {
+		log.Println("login limiter error:", err)
+	}
+	if accountWait > 0 {
+		return accountWait
+	}
+
+	ipWait, err := initializers.IPLimiter.Reserve(ipKey)
+	if err != nil {
+		log.Println("login limiter error:", err)
+	}
+	if ipWait > 0 {
+		// the attempt doesn't happen, so it doesn't count for the account either
+		initializers.AccountLimiter.Refund(accountKey)
+		return ipWait
+	}
+	return 0
+}
+
+// the attempt was wrong (it is already counted), returns the lock that started
+// with it, if any
+func loginFailed(accountKey, ipKey string) time.Duration {
+	accountLock, err := initializers.AccountLimiter.Check(accountKey)
+	if err != nil {
+		log.Println("login limiter error:", err)
+	}
+
+	ipLock, err := initializers.IPLimiter.Check(ipKey)
+	if err != nil {
+		log.Println("login limiter error:", err)
+	}
+
+	if ipLock > accountLock {
+		return ipLock
+	}
+	return accountLock
+}
+
+// the attempt was right: the account starts fresh, the IP only gets this attempt back
+// (other accounts may still be guessed from the same IP)
+func loginSucceeded(accountKey, ipKey string) {
+	if err := initializers.AccountLimiter.Reset(accountKey); err != nil {
+		log.Println("login limiter error:", err)
+	}
+	if err := initializers.IPLimiter.Refund(ipKey); err != nil {
+		log.Println("login limiter error:", err)
+	}
+}
+
+// 429 response with Retry-After header (in seconds)
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Terlalu banyak percobaan login, coba lagi nanti",
+		"retry_after": seconds,
+	})
+}
+
+// save one row in the login audit log
+func recordLoginAudit(c *gin.Context, user *models.User, email, event, details string) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	audit := models.LoginAudit{
+		Email:     email,
+		Event:     event,
+		IPAddress: c.ClientIP(),
+		UserAgent: userAgent,
+		Details:   details,
+	}
+	if user != nil && user.ID != 0 {
+		audit.UserID = &user.ID
+		audit.Email = user.Email
+	}
+
+	if err := initializers.DB.Create(&audit).Error; err != nil {
+		log.Println("failed to save login audit:", err)
+	}
+}
+
+// GET LOGIN HISTORY (own account)
+
+func GetLoginHistory(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var audits []models.LoginAudit
+	result := initializers.DB.
+		Where("user_id = ?", currentUser.ID).
+		Order("created_at desc").
+		Limit(100).
+		Find(&audits)
+
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil riwayat login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": audits})
+}
+
+// GET LOGIN AUDIT (moderator / admin)
+
+func GetLoginAudit(c *gin.Context) {
+	var audits []models.LoginAudit
+
+	db := initializers.DB.Order("created_at desc").Limit(200)
+
+	if event := c.Query("event"); event != "" {
+		db = db.Where("event = ?", event)
+	}
+	if email := c.Query("email"); email != "" {
+		db = db.Where("email = ?", email)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		db = db.Where("ip_address = ?", ip)
+	}
+
+	if result := db.Find(&audits); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil audit login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"audits": audits})
+}
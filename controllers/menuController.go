@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// body of create / update, pointers so an update can leave fields as they are
+type menuItemInput struct {
+	Name       *string `json:"name"`
+	Category   *string `json:"category"`
+	Price      *int64  `json:"price"`
+	Currency   *string `json:"currency"`
+	Vegetarian *bool   `json:"vegetarian"`
+	Vegan      *bool   `json:"vegan"`
+	GlutenFree *bool   `json:"gluten_free"`
+	DairyFree  *bool   `json:"dairy_free"`
+	Halal      *bool   `json:"halal"`
+	Available  *bool   `json:"available"`
+}
+
+// copy the sent fields into item, returns an error message for the client
+func (in menuItemInput) apply(item *models.MenuItem) string {
+	if in.Name != nil {
+		item.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Category != nil {
+		item.Category = strings.ToLower(strings.TrimSpace(*in.Category))
+	}
+	if in.Price != nil {
+		item.Price = *in.Price
+	}
+	if in.Currency != nil {
+		item.Currency = strings.ToUpper(strings.TrimSpace(*in.Currency))
+	}
+
+	setBool(&item.Vegetarian, in.Vegetarian)
+	setBool(&item.Vegan, in.Vegan)
+	setBool(&item.GlutenFree, in.GlutenFree)
+	setBool(&item.DairyFree, in.DairyFree)
+	setBool(&item.Halal, in.Halal)
+	setBool(&item.Available, in.Available)
+
+	// vegan food is always vegetarian and dairy free
+	if item.Vegan {
+		item.Vegetarian = true
+		item.DairyFree = true
+	}
+
+	switch {
+	case item.Name == "":
+		return "Nama menu wajib diisi"
+	case item.Price < 0:
+		return "Harga tidak boleh negatif"
+	case len(item.Currency) != 3:
+		return "Currency harus kode 3 huruf (contoh: IDR)"
+	}
+	return ""
+}
+
+func setBool(field *bool, value *bool) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+// GET MENU OF A CAFE
+// ?category=&available=true
+
+func GetMenu(c *gin.Context) {
+	var cafe models.Cafe
+	if err := initializers.DB.First(&cafe, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kafe tidak ditemukan"})
+		return
+	}
+
+	db := initializers.DB.Where("cafe_id = ?", cafe.ID).Order("category, name")
+	if category := c.Query("category"); category != "" {
+		db = db.Where("category = ?", strings.ToLower(category))
+	}
+	if c.Query("available") == "true" {
+		db = db.Where("available = ?", true)
+	}
+
+	var items []models.MenuItem
+	if err := db.Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil menu"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"menu_items": items})
+}
+
+// CREATE MENU ITEM (owner of the cafe or moderator)
+
+func CreateMenuItem(c *gin.Context) {
+	cafe, currentUser, ok := menuCafe(c)
+	if !ok {
+		return
+	}
+
+	var body menuItemInput
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca input menu"})
+		return
+	}
+
+	item := models.MenuItem{
+		CafeID:    cafe.ID,
+		UserID:    currentUser.ID,
+		Currency:  "IDR",
+		Available: true,
+	}
+	if msg := body.apply(&item); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		if cafe.UserID != currentUser.ID {
+			return recordModeration(tx, currentUser, "menu.create", "cafe", cafe.ID, cafe.UserID, gin.H{"after": item})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan menu"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu berhasil ditambahkan!", "menu_item": item})
+}
+
+// UPDATE MENU ITEM
+
+func UpdateMenuItem(c *gin.Context) {
+	cafe, currentUser, ok := menuCafe(c)
+	if !ok {
+		return
+	}
+
+	var item models.MenuItem
+	if err := initializers.DB.Where("cafe_id = ?", cafe.ID).First(&item, c.Param("item_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Menu tidak ditemukan"})
+		return
+	}
+
+	var body menuItemInput
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca input menu"})
+		return
+	}
+
+	before := item
+	if msg := body.apply(&item); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Select("*") so false / 0 values are saved too
+		if err := tx.Model(&item).Select("*").Omit("CreatedAt").Updates(&item).Error; err != nil {
+			return err
+		}
+		if cafe.UserID != currentUser.ID {
+			return recordModeration(tx, currentUser, "menu.update", "cafe", cafe.ID, cafe.UserID, gin.H{"before": before, "after": item})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal update menu"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu berhasil diperbarui!", "menu_item": item})
+}
+
+// DELETE MENU ITEM
+
+func DeleteMenuItem(c *gin.Context) {
+	cafe, currentUser, ok := menuCafe(c)
+	if !ok {
+		return
+	}
+
+	var item models.MenuItem
+	if err := initializers.DB.Where("cafe_id = ?", cafe.ID).First(&item, c.Param("item_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Menu tidak ditemukan"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		if cafe.UserID != currentUser.ID {
+			return recordModeration(tx, currentUser, "menu.delete", "cafe", cafe.ID, cafe.UserID, gin.H{"before": item})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus menu"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu berhasil dihapus"})
+}
+
+// load the cafe of the URL and check that the user may edit its menu
+func menuCafe(c *gin.Context) (models.Cafe, models.User, bool) {
+	var cafe models.Cafe
+	if err := initializers.DB.First(&cafe, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kafe tidak ditemukan"})
+		return cafe, models.User{}, false
+	}
+
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if !canModify(currentUser, cafe.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk mengedit menu kafe ini"})
+		return cafe, currentUser, false
+	}
+
+	return cafe, currentUser, true
+}
+
+// menu filters of the cafe list, e.g. "oat milk latte under 30k":
+// ?menu=oat milk latte&menu_max_price=30000&menu_category=coffee&menu_dietary=vegan,halal
+// all conditions must match the SAME available menu item.
+// menu_max_price is in menu_currency (default IDR), items in other currencies don't match
+func menuFilter(c *gin.Context, db *gorm.DB) (*gorm.DB, string) {
+	search := strings.TrimSpace(c.Query("menu"))
+	category := c.Query("menu_category")
+	maxPrice := c.Query("menu_max_price")
+	dietary := c.Query("menu_dietary")
+
+	if search == "" && category == "" && maxPrice == "" && dietary == "" {
+		return db, ""
+	}
+
+	sub := initializers.DB.Model(&models.MenuItem{}).Select("1").
+		Where("menu_items.cafe_id = cafes.id AND menu_items.available = ?", true)
+
+	if search != "" {
+		// every word must appear in this order, so "oat-milk latte" also finds "Oat Milk Latte"
+		words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+			return r == ' ' || r == '-' || r == '_' || r == '%'
+		})
+		sub = sub.Where("LOWER(menu_items.name) LIKE ?", "%"+strings.Join(words, "%")+"%")
+	}
+	if category != "" {
+		sub = sub.Where("menu_items.category = ?", strings.ToLower(category))
+	}
+	if maxPrice != "" {
+		price, err := strconv.ParseInt(maxPrice, 10, 64)
+		if err != nil || price < 0 {
+			return nil, "menu_max_price harus angka"
+		}
+		currency := strings.ToUpper(strings.TrimSpace(c.DefaultQuery("menu_currency", "IDR")))
+		if len(currency) != 3 {
+			return nil, "menu_currency harus kode 3 huruf (contoh: IDR)"
+		}
+		sub = sub.Where("menu_items.price <= ? AND menu_items.currency = ?", price, currency)
+	}
+	if dietary != "" {
+		for _, name := range strings.Split(dietary, ",") {
+			column, ok := models.DietaryColumns[strings.TrimSpace(name)]
+			if !ok {
+				return nil, "menu_dietary tidak dikenal: " + name
+			}
+			sub = sub.Where("menu_items."+column+" = ?", true)
+		}
+	}
+
+	return db.Where("EXISTS (?)", sub), ""
+}
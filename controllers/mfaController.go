@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// number of recovery codes given on confirm
+const recoveryCodeCount = 10
+
+// ---------------------------
+// ENROLL 2FA (step 1)
+// ---------------------------
+// create a new secret and return the otpauth URI for the authenticator app.
+// 2FA is not active yet, the user has to confirm with a code first
+func EnrollTOTP(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if currentUser.TOTPEnabledAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2FA sudah aktif"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat secret 2FA"})
+		return
+	}
+
+	if err := initializers.DB.Model(&currentUser).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan secret 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret, // for manual input in the app
+		"otpauth_uri": auth.TOTPURI("Cafetify", currentUser.Email, secret),
+	})
+}
+
+// ---------------------------
+// CONFIRM 2FA (step 2)
+// ---------------------------
+func ConfirmTOTP(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var body struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kode 2FA wajib diisi"})
+		return
+	}
+
+	if currentUser.TOTPEnabledAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2FA sudah aktif"})
+		return
+	}
+	if currentUser.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lakukan enroll 2FA terlebih dahulu"})
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(currentUser.TOTPSecret, body.Code, time.Now(), currentUser.TOTPLastStep)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kode 2FA salah"})
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat recovery code"})
+		return
+	}
+
+	// --- DATABASE TRANSACTION ---
+	tx := initializers.DB.Begin()
+
+	if err := tx.Model(&currentUser).Updates(map[string]interface{}{
+		"totp_enabled_at": time.Now(),
+		"totp_last_step":  step,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengaktifkan 2FA"})
+		return
+	}
+
+	if err := replaceRecoveryCodes(tx, currentUser.ID, codes); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan recovery code"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "2FA berhasil diaktifkan! Simpan recovery code ini, hanya ditampilkan sekali.",
+		"recovery_codes": codes,
+	})
+}
+
+// ---------------------------
+// DISABLE 2FA
+// ---------------------------
+func DisableTOTP(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var body struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+	}
+
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password dan kode 2FA wajib diisi"})
+		return
+	}
+
+	if currentUser.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2FA belum aktif"})
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(currentUser.Password), []byte(body.Password)) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password salah"})
+		return
+	}
+
+	if !verifySecondFactor(currentUser, body.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kode 2FA salah"})
+		return
+	}
+
+	tx := initializers.DB.Begin()
+
+	if err := tx.Model(&currentUser).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menonaktifkan 2FA"})
+		return
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", currentUser.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menonaktifkan 2FA"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "2FA berhasil dinonaktifkan"})
+}
+
+// ---------------------------
+// LOGIN STEP 2 (2FA challenge)
+// ---------------------------
+func LoginMFA(c *gin.Context) {
+	var body struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+	}
+
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token dan kode wajib diisi"})
+		return
+	}
+
+	claims, err := auth.ParseMFAToken(body.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesi login sudah berakhir, silakan login kembali"})
+		return
+	}
+
+	var user models.User
+	initializers.DB.First(&user, auth.ClaimUint(claims, "sub"))
+
+	if user.ID == 0 || user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesi login sudah berakhir, silakan login kembali"})
+		return
+	}
+
+	// the 6 digit code is easy to guess without a limit
+	mfaKey, ipKey := fmt.Sprintf("mfa:%d", user.ID), "ip:"+c.ClientIP()
+	if wait := reserveLoginAttempt(mfaKey, ipKey); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	if !verifySecondFactor(user, body.Code) {
+		recordLoginAudit(c, &user, user.Email, models.AuditMFAFailed, "wrong 2FA code")
+
+		if lock := loginFailed(mfaKey, ipKey); lock > 0 {
+			recordLoginAudit(c, &user, user.Email, models.AuditLockout, "2FA locked for "+lock.String())
+			tooManyAttempts(c, lock)
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kode 2FA salah"})
+		return
+	}
+
+	loginSucceeded(mfaKey, ipKey)
+
+	completeLogin(c, user)
+}
+
+// check a TOTP code, or use up one recovery code
+func verifySecondFactor(user models.User, code string) bool {
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		// save the step, the WHERE makes sure the same code can't be used twice
+		result := initializers.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	result := initializers.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, recoveryCodeHash(user.ID, code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// delete the old recovery codes and save the new ones (hashed)
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string) error {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	records := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: recoveryCodeHash(userID, code),
+		})
+	}
+
+	return tx.Create(&records).Error
+}
+
+// recovery codes are hashed with the user id, so the same code for two users
+// gives a different hash
+func recoveryCodeHash(userID uint, code string) string {
+	return auth.HashToken(fmt.Sprintf("%d:%s", userID, auth.NormalizeRecoveryCode(code)))
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// owner of the data, or a moderator / admin
+func canModify(user models.User, ownerID uint) bool {
+	return user.ID == ownerID || auth.HasPermission(user.Role, auth.PermModerateContent)
+}
+
+// save a moderation log row. only called when the actor is not the owner
+// (or for shared data like tags), normal users editing their own data are not logged
+func recordModeration(db *gorm.DB, actor models.User, action, targetType string, targetID, ownerID uint, details gin.H) error {
+	detailJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return db.Create(&models.ModerationLog{
+		ActorID:    actor.ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		OwnerID:    ownerID,
+		Details:    string(detailJSON),
+	}).Error
+}
+
+// GET MODERATION LOGS
+
+func GetModerationLogs(c *gin.Context) {
+	var logs []models.ModerationLog
+
+	db := initializers.DB.Preload("Actor").Order("created_at desc").Limit(200)
+
+	if targetType := c.Query("target_type"); targetType != "" {
+		db = db.Where("target_type = ?", targetType)
+	}
+	if actorID := c.Query("actor_id"); actorID != "" {
+		db = db.Where("actor_id = ?", actorID)
+	}
+
+	if result := db.Find(&logs); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil log moderasi"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs})
+}
+
+// UPDATE USER ROLE (admin only)
+
+func UpdateUserRole(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID user tidak valid"})
+		return
+	}
+
+	var body struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if c.ShouldBindJSON(&body) != nil || !auth.ValidRole(body.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role harus user, moderator, atau admin"})
+		return
+	}
+
+	// an admin can't remove their own admin role, so there is always one admin left
+	if uint(targetID) == currentUser.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tidak bisa mengubah role sendiri"})
+		return
+	}
+
+	var target models.User
+	if err := initializers.DB.First(&target, targetID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User tidak ditemukan"})
+		return
+	}
+
+	oldRole := target.Role
+
+	tx := initializers.DB.Begin()
+
+	if err := tx.Model(&target).Update("role", body.Role).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengubah role"})
+		return
+	}
+
+	if err := recordModeration(tx, currentUser, "user.role", "user", target.ID, target.ID, gin.H{
+		"old_role": oldRole,
+		"new_role": body.Role,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role berhasil diubah", "user": target.Account()})
+}
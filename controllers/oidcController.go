@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"github.com/rizqy/cafetify/oidc"
+	"gorm.io/gorm"
+)
+
+// time the user has to login at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// the state is also kept in the browser that started the login, so a callback
+// URL sent by someone else can't log the victim into the sender's account
+const (
+	oidcStateCookieName = "oidc_state"
+	oidcCookiePath      = "/auth/oidc/"
+)
+
+// ---------------------------
+// SOCIAL LOGIN (step 1): redirect to the provider
+// ---------------------------
+func OIDCLogin(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider, ok := initializers.OIDCProviders[providerName]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider login tidak ditemukan"})
+		return
+	}
+
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai login"})
+		return
+	}
+
+	loginState := models.OIDCLoginState{
+		StateHash:    auth.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if err := initializers.DB.Create(&loginState).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Provider login tidak bisa dihubungi"})
+		return
+	}
+
+	// Lax: the cookie is still sent when the provider redirects back (top level GET)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookieName, state, int(oidcStateTTL.Seconds()), oidcCookiePath, "", false, true)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ---------------------------
+// SOCIAL LOGIN (step 2): provider redirects back here
+// ---------------------------
+func OIDCCallback(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider, ok := initializers.OIDCProviders[providerName]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider login tidak ditemukan"})
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login dibatalkan: " + errCode})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter code / state tidak ada"})
+		return
+	}
+
+	// the login must have been started in this browser
+	cookieState, _ := c.Cookie(oidcStateCookieName)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookieName, "", -1, oidcCookiePath, "", false, true)
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sesi login tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	// the state can only be used once and only for the same provider
+	var loginState models.OIDCLoginState
+	err := initializers.DB.First(&loginState, "state_hash = ?", auth.HashToken(state)).Error
+	if err != nil || loginState.Provider != providerName || loginState.UsedAt != nil || time.Now().After(loginState.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sesi login tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	result := initializers.DB.Model(&models.OIDCLoginState{}).
+		Where("id = ? AND used_at IS NULL", loginState.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sesi login tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login dengan provider gagal"})
+		return
+	}
+
+	user, err := findOrCreateOIDCUser(providerName, claims)
+	if err != nil {
+		var msg errOIDC
+		if errors.As(err, &msg) {
+			c.JSON(http.StatusConflict, gin.H{"error": string(msg)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login dengan provider gagal"})
+		return
+	}
+
+	beginLogin(c, user)
+}
+
+// GET LINKED IDENTITIES
+
+func GetIdentities(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var identities []models.LinkedIdentity
+	if err := initializers.DB.Where("user_id = ?", currentUser.ID).Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data akun terhubung"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// find the user of the provider account:
+//  1. identity already linked -> that user
+//  2. email already registered -> link to that user (only if the provider and our
+//     own verification both confirmed the email)
+//  3. otherwise create a new user
+func findOrCreateOIDCUser(providerName string, claims *oidc.Claims) (models.User, error) {
+	var user models.User
+	now := time.Now()
+
+	// 1. already linked
+	var identity models.LinkedIdentity
+	if err := initializers.DB.First(&identity, "provider = ? AND subject = ?", providerName, claims.Subject).Error; err == nil {
+		if err := initializers.DB.First(&user, identity.UserID).Error; err != nil {
+			return user, errOIDC("Akun tidak ditemukan")
+		}
+		initializers.DB.Model(&identity).Update("last_login_at", now)
+		return user, nil
+	}
+
+	claims.Email = normalizeEmail(claims.Email)
+	if claims.Email == "" {
+		return user, errOIDC("Provider tidak mengirim email")
+	}
+
+	// an unverified email at the provider could belong to someone else
+	if !claims.EmailVerified {
+		return user, errOIDC("Email belum diverifikasi oleh provider")
+	}
+
+	tx := initializers.DB.Begin()
+
+	// 2. link to the existing account with the same email
+	err := tx.First(&user, "email = ?", claims.Email).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return user, err
+	}
+
+	// anyone can register an email without owning it. linking an unverified account
+	// would hand the provider login to whoever knows the password of that account
+	if user.ID != 0 && user.EmailVerifiedAt == nil {
+		tx.Rollback()
+		return user, errOIDC("Email sudah terdaftar tapi belum diverifikasi. Login dengan password dan verifikasi email dulu")
+	}
+
+	// 3. new account
+	if user.ID == 0 {
+		// random password, the user can set one later with forgot password
+		randomPassword, err := auth.NewOpaqueToken()
+		if err != nil {
+			tx.Rollback()
+			return user, err
+		}
+		hash, err := auth.HashPassword(randomPassword)
+		if err != nil {
+			tx.Rollback()
+			return user, err
+		}
+
+		user = models.User{
+			Email:           claims.Email,
+			Username:        oidcUsername(claims),
+			Password:        hash,
+			EmailVerifiedAt: &now,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			tx.Rollback()
+			return user, errOIDC("Gagal membuat user")
+		}
+	}
+
+	identity = models.LinkedIdentity{
+		UserID:      user.ID,
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: now,
+	}
+	if err := tx.Create(&identity).Error; err != nil {
+		tx.Rollback()
+		return user, errOIDC("Gagal menghubungkan akun")
+	}
+
+	tx.Commit()
+
+	return user, nil
+}
+
+func oidcUsername(claims *oidc.Claims) string {
+	if claims.Name != "" {
+		return claims.Name
+	}
+	return strings.SplitN(claims.Email, "@", 2)[0]
+}
+
+// error with a message that can be shown to the user
+type errOIDC string
+
+func (e errOIDC) Error() string { return string(e) }
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// "2026-10-18T19:30" without offset = that wall clock time in the timezone of each cafe
+const wallClockLayout = "2006-01-02T15:04"
+
+// open_now / open_at filter of the cafe list
+type openFilter struct {
+	at        time.Time
+	wallClock bool
+}
+
+// read ?open_now=true or ?open_at=<datetime>, nil when the request has neither
+func parseOpenFilter(c *gin.Context) (*openFilter, bool) {
+	if v := c.Query("open_at"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return &openFilter{at: t}, true
+		}
+		if t, err := time.Parse(wallClockLayout, v); err == nil {
+			return &openFilter{at: t, wallClock: true}, true
+		}
+		return nil, false
+	}
+
+	if c.Query("open_now") == "true" || c.Query("open_now") == "1" {
+		return &openFilter{at: time.Now()}, true
+	}
+
+	return nil, true
+}
+
+func (f *openFilter) match(cafe models.Cafe) bool {
+	at := f.at
+	if f.wallClock {
+		at = time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), at.Minute(), 0, 0, cafe.TimeLocation())
+	}
+	return cafe.IsOpenAt(at)
+}
+
+// preload the schedule. with a filter only the exceptions around that time are needed
+// (the date depends on the cafe timezone, so one day extra on each side),
+// without one the upcoming exceptions are shown
+func preloadOpeningHours(db *gorm.DB, filter *openFilter) *gorm.DB {
+	db = db.Preload("OpeningHours", func(db *gorm.DB) *gorm.DB {
+		return db.Order("weekday, opens")
+	})
+
+	if filter != nil {
+		from := filter.at.AddDate(0, 0, -2).Format(models.DateLayout)
+		to := filter.at.AddDate(0, 0, 1).Format(models.DateLayout)
+		return db.Preload("OpeningExceptions", "date BETWEEN ? AND ?", from, to)
+	}
+
+	from := time.Now().AddDate(0, 0, -1).Format(models.DateLayout)
+	return db.Preload("OpeningExceptions", func(db *gorm.DB) *gorm.DB {
+		return db.Where("date >= ?", from).Order("date")
+	})
+}
+
+// GET OPENING HOURS
+
+func GetOpeningHours(c *gin.Context) {
+	var cafe models.Cafe
+	if err := preloadOpeningHours(initializers.DB, nil).First(&cafe, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kafe tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timezone":           cafe.TimeLocation().String(),
+		"opening_hours":      cafe.OpeningHours,
+		"opening_exceptions": cafe.OpeningExceptions,
+		"open_now":           cafe.IsOpenAt(time.Now()),
+	})
+}
+
+// UPDATE OPENING HOURS
+// replaces the whole schedule, send the full week every time.
+// timezone is only changed when it is in the body ("" = back to the default)
+
+func UpdateOpeningHours(c *gin.Context) {
+	var body struct {
+		Timezone *string `json:"timezone"`
+		Weekly   []struct {
+			Weekday int    `json:"weekday"`
+			Opens   string `json:"opens" binding:"required"`
+			Closes  string `json:"closes" binding:"required"`
+		} `json:"weekly"`
+		Exceptions []struct {
+			Date   string `json:"date" binding:"required"`
+			Closed bool   `json:"closed"`
+			Opens  string `json:"opens"`
+			Closes string `json:"closes"`
+			Note   string `json:"note"`
+		} `json:"exceptions"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input jam buka tidak lengkap: " + err.Error()})
+		return
+	}
+
+	var cafe models.Cafe
+	if err := initializers.DB.First(&cafe, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kafe tidak ditemukan"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+	if !canModify(currentUser, cafe.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk mengedit kafe ini"})
+		return
+	}
+
+	if body.Timezone != nil && *body.Timezone != "" {
+		if _, err := models.LoadTimezone(*body.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Timezone tidak dikenal, contoh: Asia/Jakarta"})
+			return
+		}
+	}
+
+	hours := make([]models.OpeningHour, 0, len(body.Weekly))
+	for _, h := range body.Weekly {
+		if h.Weekday < 0 || h.Weekday > 6 || !validClockRange(h.Opens, h.Closes) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Jam buka tidak valid (weekday 0-6, jam HH:MM)"})
+			return
+		}
+		hours = append(hours, models.OpeningHour{
+			CafeID:  cafe.ID,
+			Weekday: time.Weekday(h.Weekday),
+			Opens:   h.Opens,
+			Closes:  h.Closes,
+		})
+	}
+
+	exceptions := make([]models.OpeningException, 0, len(body.Exceptions))
+	for _, e := range body.Exceptions {
+		if _, err := time.Parse(models.DateLayout, e.Date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tanggal harus YYYY-MM-DD"})
+			return
+		}
+		if !e.Closed && !validClockRange(e.Opens, e.Closes) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Isi jam buka tanggal " + e.Date + ", atau set closed"})
+			return
+		}
+		exceptions = append(exceptions, models.OpeningException{
+			CafeID: cafe.ID,
+			Date:   e.Date,
+			Closed: e.Closed,
+			Opens:  e.Opens,
+			Closes: e.Closes,
+			Note:   e.Note,
+		})
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if body.Timezone != nil {
+			if err := tx.Model(&cafe).Update("timezone", *body.Timezone).Error; err != nil {
+				return err
+			}
+			cafe.Timezone = *body.Timezone
+		}
+		if err := tx.Unscoped().Where("cafe_id = ?", cafe.ID).Delete(&models.OpeningHour{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("cafe_id = ?", cafe.ID).Delete(&models.OpeningException{}).Error; err != nil {
+			return err
+		}
+		if len(hours) > 0 {
+			if err := tx.Create(&hours).Error; err != nil {
+				return err
+			}
+		}
+		if len(exceptions) > 0 {
+			if err := tx.Create(&exceptions).Error; err != nil {
+				return err
+			}
+		}
+
+		if cafe.UserID != currentUser.ID {
+			return recordModeration(tx, currentUser, "cafe.hours", "cafe", cafe.ID, cafe.UserID, gin.H{
+				"timezone":   body.Timezone,
+				"weekly":     body.Weekly,
+				"exceptions": body.Exceptions,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan jam buka"})
+		return
+	}
+
+	cafe.OpeningHours = hours
+	cafe.OpeningExceptions = exceptions
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Jam buka berhasil disimpan",
+		"timezone":           cafe.TimeLocation().String(),
+		"opening_hours":      hours,
+		"opening_exceptions": exceptions,
+		"open_now":           cafe.IsOpenAt(time.Now()),
+	})
+}
+
+// both HH:MM, same opens and closes is only allowed as 00:00 - 00:00 (24 hours)
+func validClockRange(opens, closes string) bool {
+	start, err1 := models.ParseClock(opens)
+	end, err2 := models.ParseClock(closes)
+	if err1 != nil || err2 != nil || start >= 24*60 {
+		return false
+	}
+	return start != end || start == 0
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/models"
+)
+
+func TestValidClockRange(t *testing.T) {
+	tests := []struct {
+		opens, closes string
+		want          bool
+	}{
+		{"08:00", "17:00", true},
+		{"18:00", "02:00", true}, // over midnight
+		{"08:00", "24:00", true},
+		{"00:00", "00:00", true}, // whole day
+		{"08:00", "08:00", false},
+		{"24:00", "02:00", false},
+		{"8am", "17:00", false},
+		{"08:00", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := validClockRange(tt.opens, tt.closes); got != tt.want {
+			t.Errorf("validClockRange(%q, %q) = %v, want %v", tt.opens, tt.closes, got, tt.want)
+		}
+	}
+}
+
+func TestParseOpenFilter(t *testing.T) {
+	tests := []struct {
+		query     string
+		ok        bool
+		filter    bool
+		wallClock bool
+	}{
+		{"", true, false, false},
+		{"open_now=true", true, true, false},
+		{"open_now=false", true, false, false},
+		{"open_at=2026-10-16T19:30:00%2B07:00", true, true, false},
+		{"open_at=2026-10-16T19:30", true, true, true},
+		{"open_at=tomorrow", false, false, false},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/cafes?"+tt.query, nil)
+
+		filter, ok := parseOpenFilter(c)
+		if ok != tt.ok || (filter != nil) != tt.filter || (filter != nil && filter.wallClock != tt.wallClock) {
+			t.Errorf("parseOpenFilter(%q) = %+v, %v", tt.query, filter, ok)
+		}
+	}
+}
+
+// a wall clock open_at means that local time in every cafe, not one instant
+func TestOpenFilterWallClock(t *testing.T) {
+	schedule := []models.OpeningHour{{Weekday: time.Friday, Opens: "18:00", Closes: "22:00"}}
+	jakarta := models.Cafe{Timezone: "Asia/Jakarta", OpeningHours: schedule}
+	jayapura := models.Cafe{Timezone: "Asia/Jayapura", OpeningHours: schedule} // UTC+9
+
+	// 21:30 in every cafe
+	wallClock := &openFilter{at: time.Date(2026, 10, 16, 21, 30, 0, 0, time.UTC), wallClock: true}
+	// 21:30 Jakarta = 23:30 Jayapura
+	instant := &openFilter{at: time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)}
+	// 17:30 Jakarta = 19:30 Jayapura
+	early := &openFilter{at: time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name   string
+		filter *openFilter
+		cafe   models.Cafe
+		want   bool
+	}{
+		{"wall clock in jakarta", wallClock, jakarta, true},
+		{"wall clock in jayapura", wallClock, jayapura, true},
+		{"instant in jakarta", instant, jakarta, true},
+		{"instant in jayapura", instant, jayapura, false},
+		{"early instant in jakarta", early, jakarta, false},
+		{"early instant in jayapura", early, jayapura, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.match(tt.cafe); got != tt.want {
+			t.Errorf("%s: match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/mailer"
+	"github.com/rizqy/cafetify/models"
+)
+
+// reset link is valid for 1 hour
+const resetTokenTTL = time.Hour
+
+// ---------------------------
+// FORGOT PASSWORD
+// ---------------------------
+func ForgotPassword(c *gin.Context) {
+	var body struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email wajib diisi"})
+		return
+	}
+
+	// always return the same response, so nobody can check which email is registered
+	response := gin.H{"message": "Jika email terdaftar, link reset password sudah dikirim"}
+
+	var user models.User
+	initializers.DB.First(&user, "email = ?", normalizeEmail(body.Email))
+
+	if user.ID == 0 {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat token reset"})
+		return
+	}
+
+	now := time.Now()
+
+	// only the newest link should work
+	initializers.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now)
+
+	reset := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: now.Add(resetTokenTTL),
+	}
+	if err := initializers.DB.Create(&reset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat token reset"})
+		return
+	}
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset password Cafetify",
+		Body: fmt.Sprintf("Halo %s,\n\nKlik link berikut untuk membuat password baru (berlaku 1 jam):\n%s\n\nAbaikan email ini jika kamu tidak meminta reset password.",
+			user.Username, frontendURL("/reset-password", token)),
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ---------------------------
+// RESET PASSWORD
+// ---------------------------
+func ResetPassword(c *gin.Context) {
+	var body struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	if c.ShouldBindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token dan password baru wajib diisi"})
+		return
+	}
+
+	var reset models.PasswordResetToken
+	err := initializers.DB.First(&reset, "token_hash = ?", auth.HashToken(body.Token)).Error
+	if err != nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link reset tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	if problems := auth.PasswordPolicyFromEnv().Check(body.NewPassword); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password baru tidak memenuhi syarat", "details": problems})
+		return
+	}
+
+	// hash new password
+	newHash, err := auth.HashPassword(body.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses password baru"})
+		return
+	}
+
+	// --- DATABASE TRANSACTION ---
+	tx := initializers.DB.Begin()
+
+	// mark token as used, the WHERE makes sure it can only be used once
+	result := tx.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link reset tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", newHash).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan password baru"})
+		return
+	}
+
+	tx.Commit()
+
+	// someone may know the old password, log out everywhere
+	auth.RevokeAllForUser(reset.UserID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password berhasil direset! Silakan login."})
+}
+
+// send email in the background, the request doesn't have to wait for SMTP
+func sendMail(msg mailer.Message) {
+	go func() {
+		if err := initializers.Mailer.Send(msg); err != nil {
+			log.Printf("failed to send email to %s: %v", msg.To, err)
+		}
+	}()
+}
+
+// link to a page of the React app, e.g. http://localhost:5173/reset-password?token=...
+func frontendURL(path, token string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/") + path + "?token=" + url.QueryEscape(token)
+}
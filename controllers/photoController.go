@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"github.com/rizqy/cafetify/photo"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPhotoMaxBytes = 5 << 20 // 5 MB
+	storageTimeout       = 30 * time.Second
+)
+
+// max size of one upload (PHOTO_MAX_BYTES, default 5 MB)
+func photoMaxBytes() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("PHOTO_MAX_BYTES"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultPhotoMaxBytes
+}
+
+// UPLOAD CAFE PHOTO (multipart, field "photo")
+
+func UploadCafePhoto(c *gin.Context) {
+	var cafe models.Cafe
+	if err := initializers.DB.First(&cafe, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kafe tidak ditemukan"})
+		return
+	}
+
+	savePhoto(c, cafe.ID, nil)
+}
+
+// UPLOAD RATING PHOTO (only the author of the rating)
+
+func UploadRatingPhoto(c *gin.Context) {
+	var rating models.PersonalRating
+	if err := initializers.DB.First(&rating, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rating tidak ditemukan"})
+		return
+	}
+
+	userContext, _ := c.Get("user")
+	if rating.UserID != userContext.(models.User).ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya penulis rating yang bisa menambah foto"})
+		return
+	}
+
+	savePhoto(c, rating.CafeID, &rating.ID)
+}
+
+func savePhoto(c *gin.Context, cafeID uint, ratingID *uint) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	maxBytes := photoMaxBytes()
+	// some room for the other multipart fields / boundaries
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Ukuran foto terlalu besar"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File foto wajib diisi (field \"photo\")"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca file foto"})
+		return
+	}
+	defer file.Close()
+
+	processed, err := photo.Process(file, maxBytes)
+	switch {
+	case errors.Is(err, photo.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Ukuran foto terlalu besar"})
+		return
+	case errors.Is(err, photo.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Foto harus JPEG, PNG atau GIF"})
+		return
+	case errors.Is(err, photo.ErrTooManyPixels):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Resolusi foto terlalu besar"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses foto"})
+		return
+	}
+
+	name, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan foto"})
+		return
+	}
+	key := "photos/" + strconv.FormatUint(uint64(cafeID), 10) + "/" + name + ".jpg"
+	thumbKey := "photos/" + strconv.FormatUint(uint64(cafeID), 10) + "/" + name + "_thumb.jpg"
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), storageTimeout)
+	defer cancel()
+
+	if err := initializers.Storage.Put(ctx, key, bytes.NewReader(processed.Full), int64(len(processed.Full)), "image/jpeg"); err != nil {
+		log.Println("photo upload failed:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Gagal menyimpan foto"})
+		return
+	}
+	if err := initializers.Storage.Put(ctx, thumbKey, bytes.NewReader(processed.Thumb), int64(len(processed.Thumb)), "image/jpeg"); err != nil {
+		log.Println("thumbnail upload failed:", err)
+		deletePhotoFiles(key)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Gagal menyimpan foto"})
+		return
+	}
+
+	record := models.Photo{
+		UserID:       currentUser.ID,
+		CafeID:       cafeID,
+		RatingID:     ratingID,
+		Key:          key,
+		ThumbKey:     thumbKey,
+		URL:          initializers.Storage.URL(key),
+		ThumbnailURL: initializers.Storage.URL(thumbKey),
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Size:         int64(len(processed.Full)),
+	}
+	if err := initializers.DB.Create(&record).Error; err != nil {
+		deletePhotoFiles(key, thumbKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan foto"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Foto berhasil diunggah!", "photo": record})
+}
+
+// DELETE PHOTO (uploader or moderator)
+
+func DeletePhoto(c *gin.Context) {
+	var record models.Photo
+	if err := initializers.DB.First(&record, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Foto tidak ditemukan"})
+		return
+	}
+
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if !canModify(currentUser, record.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk menghapus foto ini"})
+		return
+	}
+
+	tx := initializers.DB.Begin()
+
+	if err := tx.Unscoped().Delete(&record).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus foto"})
+		return
+	}
+
+	if record.UserID != currentUser.ID {
+		if err := recordModeration(tx, currentUser, "photo.delete", "photo", record.ID, record.UserID, gin.H{
+			"cafe_id": record.CafeID,
+			"url":     record.URL,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+			return
+		}
+	}
+
+	tx.Commit()
+
+	// the row is gone, a file that fails to delete is only wasted space
+	deletePhotoFiles(record.Key, record.ThumbKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Foto berhasil dihapus"})
+}
+
+// delete the photo rows matching the query (e.g. "rating_id = ?"), returns the storage
+// keys so the files can be removed with deletePhotoFiles after the commit
+func deletePhotoRows(tx *gorm.DB, query string, args ...interface{}) ([]string, error) {
+	var photos []models.Photo
+	if err := tx.Where(query, args...).Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	if len(photos) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(photos)*2)
+	ids := make([]uint, len(photos))
+	for i, p := range photos {
+		keys = append(keys, p.Key, p.ThumbKey)
+		ids[i] = p.ID
+	}
+	return keys, tx.Unscoped().Delete(&models.Photo{}, ids).Error
+}
+
+func deletePhotoFiles(keys ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+
+	for _, key := range keys {
+		if err := initializers.Storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete %s: %v", key, err)
+		}
+	}
+}
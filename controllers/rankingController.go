@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/geo"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+const (
+	defaultTopLimit = 20
+	maxTopLimit     = 100
+
+	// every cafe starts with this many "virtual" ratings at the prior mean,
+	// so a cafe needs real ratings before it can beat the average
+	defaultPriorWeight = 10.0
+	// prior mean when there are no ratings at all yet
+	fallbackPriorMean = 3.0
+
+	// trending: a rating counts half after this long, ratings older than the window are ignored
+	defaultTrendingHalfLife = 7 * 24 * time.Hour
+	defaultTrendingWindow   = 30 * 24 * time.Hour
+)
+
+// RANKING_PRIOR_WEIGHT, default 10
+func rankingPriorWeight() float64 {
+	if w, err := strconv.ParseFloat(os.Getenv("RANKING_PRIOR_WEIGHT"), 64); err == nil && w >= 0 {
+		return w
+	}
+	return defaultPriorWeight
+}
+
+// RANKING_PRIOR_MEAN (1 - 5), default the mean of every rating
+func rankingPriorMean() (float64, error) {
+	if m, err := strconv.ParseFloat(os.Getenv("RANKING_PRIOR_MEAN"), 64); err == nil && m >= 1 && m <= 5 {
+		return m, nil
+	}
+
+	var mean sql.NullFloat64
+	if err := initializers.DB.Model(&models.CafeRatingStats{}).
+		Select("SUM(rating_count * (ambience_mean + service_mean) / 2) / SUM(rating_count)").
+		Scan(&mean).Error; err != nil {
+		return 0, err
+	}
+	if !mean.Valid {
+		return fallbackPriorMean, nil
+	}
+	return mean.Float64, nil
+}
+
+// TRENDING_HALF_LIFE and TRENDING_WINDOW (Go durations like 168h)
+func trendingDurations() (time.Duration, time.Duration) {
+	halfLife, window := defaultTrendingHalfLife, defaultTrendingWindow
+	if d, err := time.ParseDuration(os.Getenv("TRENDING_HALF_LIFE")); err == nil && d > 0 {
+		halfLife = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("TRENDING_WINDOW")); err == nil && d > 0 {
+		window = d
+	}
+	return halfLife, window
+}
+
+// GET TOP CAFES
+// ?mode=top|trending&tag=&city=&bbox=minLng,minLat,maxLng,maxLat&limit=
+//
+// score = (C * m + n * avg) / (C + n), a bayesian average: C virtual ratings at the
+// prior mean m are added to the n real ones, so one 5 star rating doesn't beat
+// fifty ratings of 4.5. avg is the mean of (ambience + service) / 2.
+// trending uses the same formula, but every rating of the window counts
+// 0.5^(age / half life) instead of 1, so recent ratings decide the order
+
+func GetTopCafes(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "top")
+	if mode != "top" && mode != "trending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode harus top atau trending"})
+		return
+	}
+
+	limit := defaultTopLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit tidak valid"})
+			return
+		}
+		limit = min(n, maxTopLimit)
+	}
+
+	priorWeight := rankingPriorWeight()
+	priorMean, err := rankingPriorMean()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung ranking"})
+		return
+	}
+
+	db := initializers.DB.Model(&models.Cafe{})
+	ranking := gin.H{"mode": mode, "prior_weight": priorWeight, "prior_mean": priorMean}
+
+	if mode == "top" {
+		db = db.Joins("JOIN cafe_rating_stats rs ON rs.cafe_id = cafes.id").
+			Where("rs.rating_count > 0").
+			Select("cafes.id, (? * ? + rs.rating_count * (rs.ambience_mean + rs.service_mean) / 2) / (? + rs.rating_count) AS score, rs.rating_count AS weight",
+				priorWeight, priorMean, priorWeight)
+	} else {
+		halfLife, window := trendingDurations()
+		now := time.Now()
+		decay := math.Ln2 / halfLife.Seconds()
+
+		weight := "EXP(-? * TIMESTAMPDIFF(SECOND, created_at, ?))"
+		recent := initializers.DB.Model(&models.PersonalRating{}).
+			Select("cafe_id, SUM("+weight+") AS weight, SUM("+weight+" * (ambience_rating + service_rating) / 2) AS weighted_sum",
+				decay, now, decay, now).
+			Where("created_at >= ?", now.Add(-window)).
+			Group("cafe_id")
+
+		db = db.Joins("JOIN (?) AS tr ON tr.cafe_id = cafes.id", recent).
+			Select("cafes.id, (? * ? + tr.weighted_sum) / (? + tr.weight) AS score, tr.weight AS weight",
+				priorWeight, priorMean, priorWeight)
+
+		ranking["half_life"] = halfLife.String()
+		ranking["window"] = window.String()
+	}
+
+	if tagFilter := c.Query("tag"); tagFilter != "" && tagFilter != "Semua" {
+		db = db.Joins("JOIN cafe_tags ON cafe_tags.cafe_id = cafes.id").
+			Joins("JOIN tags ON tags.id = cafe_tags.tag_id").
+			Where("tags.name = ?", tagFilter)
+	}
+
+	if city := c.Query("city"); city != "" {
+		db = db.Where("cafes.city = ?", city)
+	}
+
+	if v := c.Query("bbox"); v != "" {
+		box, err := geo.ParseBBox(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bbox tidak valid: " + err.Error()})
+			return
+		}
+		db = db.Where("cafes.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat).
+			Where("cafes.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	}
+
+	// 1. ids + scores of the leaderboard, a tie goes to the cafe with more (recent) ratings
+	var scores []struct {
+		ID     uint
+		Score  float64
+		Weight float64
+	}
+	if err := db.Order("score DESC, weight DESC, cafes.id").Limit(limit).Scan(&scores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung ranking"})
+		return
+	}
+
+	// 2. the cafes, only the light relations
+	ids := make([]uint, len(scores))
+	for i, s := range scores {
+		ids[i] = s.ID
+	}
+
+	var cafes []models.Cafe
+	if len(ids) > 0 {
+		if err := initializers.DB.Preload("Tags").Preload("Stats").Preload("Photos").Find(&cafes, ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+	}
+
+	position := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	sort.Slice(cafes, func(i, j int) bool { return position[cafes[i].ID] < position[cafes[j].ID] })
+
+	rows := make([]map[string]json.RawMessage, len(cafes))
+	for i, cafe := range cafes {
+		if rows[i], err = projectCafe(cafe, nil, []string{"tags", "stats", "photos"}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kafe"})
+			return
+		}
+		s := scores[position[cafe.ID]]
+		rows[i]["rank"], _ = json.Marshal(i + 1)
+		rows[i]["score"], _ = json.Marshal(s.Score)
+		if mode == "trending" {
+			rows[i]["recent_weight"], _ = json.Marshal(s.Weight)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cafes": rows, "ranking": ranking})
+}
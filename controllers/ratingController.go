@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+// UPDATE RATING
+
+func UpdateRating(c *gin.Context) {
+	ratingID := c.Param("id")
+
+	var body struct {
+		AmbienceRating int    `json:"ambience_rating"`
+		ServiceRating  int    `json:"service_rating"`
+		PriceLevel     string `json:"price_level"`
+		MenuVariety    string `json:"menu_variety"`
+		Notes          string `json:"notes"`
+	}
+
+	if c.BindJSON(&body) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca input rating"})
+		return
+	}
+
+	var rating models.PersonalRating
+	if err := initializers.DB.First(&rating, ratingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rating tidak ditemukan"})
+		return
+	}
+
+	// Check ownership (or moderator)
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if !canModify(currentUser, rating.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk mengedit rating ini"})
+		return
+	}
+
+	before := rating
+
+	tx := initializers.DB.Begin()
+
+	if err := tx.Model(&rating).Updates(map[string]interface{}{
+		"AmbienceRating": body.AmbienceRating,
+		"ServiceRating":  body.ServiceRating,
+		"PriceLevel":     body.PriceLevel,
+		"MenuVariety":    body.MenuVariety,
+		"Notes":          body.Notes,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal update rating"})
+		return
+	}
+
+	if rating.UserID != currentUser.ID {
+		if err := recordModeration(tx, currentUser, "rating.update", "rating", rating.ID, rating.UserID, gin.H{
+			"before": before,
+			"after":  rating,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+			return
+		}
+	}
+
+	tx.Commit()
+	stats := refreshRatingStats(rating.CafeID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rating berhasil diperbarui!", "rating": rating, "stats": stats})
+}
+
+// DELETE RATING
+
+func DeleteRating(c *gin.Context) {
+	ratingID := c.Param("id")
+
+	var rating models.PersonalRating
+	if err := initializers.DB.First(&rating, ratingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rating tidak ditemukan"})
+		return
+	}
+
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if !canModify(currentUser, rating.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki izin untuk menghapus rating ini"})
+		return
+	}
+
+	tx := initializers.DB.Begin()
+
+	if err := tx.Delete(&rating).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus rating"})
+		return
+	}
+
+	// photos of a removed rating must not stay public
+	photoKeys, err := deletePhotoRows(tx, "rating_id = ?", rating.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus rating"})
+		return
+	}
+
+	if rating.UserID != currentUser.ID {
+		if err := recordModeration(tx, currentUser, "rating.delete", "rating", rating.ID, rating.UserID, gin.H{
+			"before": rating,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+			return
+		}
+	}
+
+	tx.Commit()
+	deletePhotoFiles(photoKeys...)
+	refreshRatingStats(rating.CafeID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rating berhasil dihapus"})
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// recompute the stats after a rating of the cafe changed. the rating itself is
+// already saved, so a failure is only logged (the next change fixes the stats)
+func refreshRatingStats(cafeID uint) *models.CafeRatingStats {
+	stats, err := models.RefreshRatingStats(initializers.DB, cafeID)
+	if err != nil {
+		log.Printf("failed to refresh rating stats of cafe %d: %v", cafeID, err)
+		return nil
+	}
+	return &stats
+}
+
+// rating filters of the cafe list, on the stored stats:
+//
+//	?min_ambience=4&min_service=3.5&min_ratings=10
+//
+// min_ambience / min_service compare with the mean (1 - 5)
+func ratingStatsFilters(c *gin.Context, db *gorm.DB) (*gorm.DB, string) {
+	for param, column := range map[string]string{
+		"min_ambience": "ambience_mean",
+		"min_service":  "service_mean",
+	} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil || value < 0 || value > 5 {
+			return nil, param + " harus angka 0 - 5"
+		}
+		db = db.Where("cafes.id IN (?)", initializers.DB.Model(&models.CafeRatingStats{}).
+			Select("cafe_id").Where(column+" >= ?", value))
+	}
+
+	if v := c.Query("min_ratings"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil || count < 0 {
+			return nil, "min_ratings harus angka"
+		}
+		db = db.Where("cafes.id IN (?)", initializers.DB.Model(&models.CafeRatingStats{}).
+			Select("cafe_id").Where("rating_count >= ?", count))
+	}
+
+	return db, ""
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// create the session record for a new login.
+// also returns the CSRF token of the session (only the hash is saved)
+func startSession(c *gin.Context, userID uint) (models.Session, string, error) {
+	familyID, err := auth.NewOpaqueToken()
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	csrfToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	session := models.Session{
+		UserID:        userID,
+		FamilyID:      familyID,
+		CSRFTokenHash: auth.HashToken(csrfToken),
+		UserAgent:     userAgent,
+		IPAddress:     c.ClientIP(),
+		LastSeenAt:    time.Now(),
+	}
+
+	err = initializers.DB.Create(&session).Error
+	return session, csrfToken, err
+}
+
+// revoke one session and the refresh tokens of it
+func endSession(sessionID uint) error {
+	if sessionID == 0 {
+		return nil
+	}
+
+	var session models.Session
+	if err := initializers.DB.First(&session, sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return revokeRefreshFamily(session.FamilyID)
+}
+
+// GET SESSIONS (where am I logged in?)
+
+func GetSessions(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var sessions []models.Session
+	result := initializers.DB.
+		Where("user_id = ? AND revoked_at IS NULL", currentUser.ID).
+		Order("last_seen_at desc").
+		Find(&sessions)
+
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data sesi"})
+		return
+	}
+
+	// mark the session of this request so the UI can show "this device"
+	currentSessionID := c.GetUint("session_id")
+
+	list := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, gin.H{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"ip_address":   s.IPAddress,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"current":      s.ID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": list})
+}
+
+// DELETE SESSION (log out one device)
+
+func DeleteSession(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	var session models.Session
+	if err := initializers.DB.First(&session, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesi tidak ditemukan"})
+		return
+	}
+
+	// users can only see and revoke their own sessions
+	if session.UserID != currentUser.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesi tidak ditemukan"})
+		return
+	}
+
+	if err := endSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus sesi"})
+		return
+	}
+
+	// revoking the device you are using is the same as logout
+	if session.ID == c.GetUint("session_id") {
+		clearAuthCookies(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sesi berhasil dihapus"})
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rizqy/cafetify/initializers"
@@ -17,3 +18,89 @@ func GetAllTags(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"tags": tags})
 }
+
+// UPDATE TAG (rename, moderator only)
+
+func UpdateTag(c *gin.Context) {
+	var body struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if c.ShouldBindJSON(&body) != nil || strings.TrimSpace(body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nama tag wajib diisi"})
+		return
+	}
+
+	var tag models.Tag
+	if err := initializers.DB.First(&tag, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag tidak ditemukan"})
+		return
+	}
+
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	oldName := tag.Name
+
+	tx := initializers.DB.Begin()
+
+	if err := tx.Model(&tag).Update("name", strings.TrimSpace(body.Name)).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Gagal update tag (nama mungkin sudah ada)"})
+		return
+	}
+
+	if err := recordModeration(tx, currentUser, "tag.update", "tag", tag.ID, 0, gin.H{
+		"old_name": oldName,
+		"new_name": tag.Name,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag berhasil diperbarui!", "tag": tag})
+}
+
+// DELETE TAG (moderator only)
+
+func DeleteTag(c *gin.Context) {
+	var tag models.Tag
+	if err := initializers.DB.First(&tag, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag tidak ditemukan"})
+		return
+	}
+
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	tx := initializers.DB.Begin()
+
+	// remove the tag from every cafe first
+	if err := tx.Exec("DELETE FROM cafe_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus relasi tag"})
+		return
+	}
+
+	// hard delete, otherwise the unique name can't be created again later
+	if err := tx.Unscoped().Delete(&tag).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus tag"})
+		return
+	}
+
+	if err := recordModeration(tx, currentUser, "tag.delete", "tag", tag.ID, 0, gin.H{
+		"name": tag.Name,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan log moderasi"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag berhasil dihapus"})
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+const (
+	refreshCookieName = "RefreshToken"
+
+	// not HttpOnly: the frontend reads it and sends it back in the X-CSRF-Token header
+	csrfCookieName = "csrf_token"
+)
+
+// create a new access token + refresh token for a login session.
+// on login the session is new, on /refresh it is the same session again
+func issueTokens(c *gin.Context, session models.Session) (string, string, error) {
+	accessToken, err := auth.GenerateAccessToken(session.UserID, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	record := models.RefreshToken{
+		UserID:    session.UserID,
+		FamilyID:  session.FamilyID,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL()),
+	}
+	if err := initializers.DB.Create(&record).Error; err != nil {
+		return "", "", err
+	}
+
+	// send both tokens in cookie
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("Authorization", accessToken, int(auth.AccessTokenTTL().Seconds()), "", "", false, true)
+	c.SetCookie(refreshCookieName, refreshToken, int(auth.RefreshTokenTTL().Seconds()), "", "", false, true)
+
+	return accessToken, refreshToken, nil
+}
+
+// ---------------------------
+// REFRESH TOKEN
+// ---------------------------
+func Refresh(c *gin.Context) {
+	// refresh token from cookie, or from json body (for Postman / scripts)
+	refreshToken, _ := c.Cookie(refreshCookieName)
+	if refreshToken == "" {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		c.ShouldBindJSON(&body)
+		refreshToken = body.RefreshToken
+	}
+
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token tidak ditemukan"})
+		return
+	}
+
+	var record models.RefreshToken
+	if err := initializers.DB.First(&record, "token_hash = ?", auth.HashToken(refreshToken)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token tidak valid"})
+		return
+	}
+
+	now := time.Now()
+
+	// token was already rotated (or revoked) -> someone is reusing an old token.
+	// kill the whole family so the attacker and the victim both have to login again
+	if record.RotatedAt != nil || record.RevokedAt != nil {
+		if err := revokeRefreshFamily(record.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses refresh token"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token sudah dipakai, silakan login kembali"})
+		return
+	}
+
+	if now.After(record.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session Expired, Please Login Again"})
+		return
+	}
+
+	// mark as rotated, the WHERE makes sure two parallel requests can't both win
+	result := initializers.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", record.ID).
+		Update("rotated_at", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses refresh token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		if err := revokeRefreshFamily(record.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses refresh token"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token sudah dipakai, silakan login kembali"})
+		return
+	}
+
+	// the session of this login must still be active
+	var session models.Session
+	err := initializers.DB.First(&session, "family_id = ?", record.FamilyID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses refresh token"})
+		return
+	}
+	if err != nil || session.RevokedAt != nil {
+		if err := revokeRefreshFamily(record.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memproses refresh token"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesi sudah berakhir, silakan login kembali"})
+		return
+	}
+
+	accessToken, newRefreshToken, err := issueTokens(c, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         accessToken,
+		"refresh_token": newRefreshToken,
+		"expires_in":    int(auth.AccessTokenTTL().Seconds()),
+	})
+}
+
+// revoke every refresh token that belongs to the same login, and the session
+// itself so the access tokens of that login stop working too
+func revokeRefreshFamily(familyID string) error {
+	now := time.Now()
+
+	return initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// ---------------------------
+// LOGOUT
+// ---------------------------
+// always succeed, even if the token is already expired or invalid,
+// the client should end up logged out anyway
+func Logout(c *gin.Context) {
+	// put the access token in the revocation list
+	if tokenString, _ := auth.TokenFromRequest(c); tokenString != "" {
+		if claims, err := auth.ParseAccessToken(tokenString); err == nil {
+			jti, _ := claims["jti"].(string)
+			if err := auth.RevokeToken(jti, auth.TokenExpiry(claims)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout"})
+				return
+			}
+			if err := endSession(auth.ClaimUint(claims, "sid")); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout"})
+				return
+			}
+		}
+	}
+
+	// revoke the refresh token family of this login
+	refreshToken, _ := c.Cookie(refreshCookieName)
+	if refreshToken == "" {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		c.ShouldBindJSON(&body)
+		refreshToken = body.RefreshToken
+	}
+	if refreshToken != "" {
+		var record models.RefreshToken
+		if err := initializers.DB.First(&record, "token_hash = ?", auth.HashToken(refreshToken)).Error; err == nil {
+			if err := revokeRefreshFamily(record.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout"})
+				return
+			}
+		}
+	}
+
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout berhasil"})
+}
+
+// log out from every device
+func LogoutAll(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if err := auth.RevokeAllForUser(currentUser.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal logout dari semua sesi"})
+		return
+	}
+
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Berhasil logout dari semua sesi"})
+}
+
+// delete the token cookies from the browser
+func clearAuthCookies(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("Authorization", "", -1, "", "", false, true)
+	c.SetCookie(refreshCookieName, "", -1, "", "", false, true)
+	c.SetCookie(csrfCookieName, "", -1, "", "", false, false)
+}
+
+// the CSRF token lives as long as the session (same as the refresh token)
+func setCSRFCookie(c *gin.Context, csrfToken string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(csrfCookieName, csrfToken, int(auth.RefreshTokenTTL().Seconds()), "", "", false, false)
+}
+
+// ---------------------------
+// JWKS (public keys)
+// ---------------------------
+// other services can verify Cafetify tokens with these keys,
+// only RSA / Ed25519 keys are listed (HMAC secrets stay secret)
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.JWKS())
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/mailer"
+	"github.com/rizqy/cafetify/models"
+)
+
+// verification link is valid for 1 day
+const verifyTokenTTL = 24 * time.Hour
+
+// create a verification token and email the link to the user
+func sendVerificationEmail(user models.User) error {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// old links stop working when a new one is sent
+	initializers.DB.Model(&models.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now)
+
+	record := models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: now.Add(verifyTokenTTL),
+	}
+	if err := initializers.DB.Create(&record).Error; err != nil {
+		return err
+	}
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verifikasi email Cafetify",
+		Body: fmt.Sprintf("Halo %s,\n\nKlik link berikut untuk memverifikasi email kamu (berlaku 24 jam):\n%s",
+			user.Username, frontendURL("/verify-email", token)),
+	})
+
+	return nil
+}
+
+// ---------------------------
+// VERIFY EMAIL
+// ---------------------------
+func VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token verifikasi tidak ditemukan"})
+		return
+	}
+
+	var record models.EmailVerificationToken
+	err := initializers.DB.First(&record, "token_hash = ?", auth.HashToken(token)).Error
+	if err != nil || record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link verifikasi tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	var user models.User
+	if err := initializers.DB.First(&user, record.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link verifikasi tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	// email was changed after the link was sent
+	if user.Email != record.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link verifikasi tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	now := time.Now()
+
+	// --- DATABASE TRANSACTION ---
+	tx := initializers.DB.Begin()
+
+	result := tx.Model(&models.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link verifikasi tidak valid atau sudah kadaluarsa"})
+		return
+	}
+
+	if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memverifikasi email"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email berhasil diverifikasi!"})
+}
+
+// ---------------------------
+// RESEND VERIFICATION EMAIL
+// ---------------------------
+func ResendVerification(c *gin.Context) {
+	userContext, _ := c.Get("user")
+	currentUser := userContext.(models.User)
+
+	if currentUser.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email sudah terverifikasi"})
+		return
+	}
+
+	if err := sendVerificationEmail(currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengirim email verifikasi"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verifikasi sudah dikirim ulang"})
+}
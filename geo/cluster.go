@@ -0,0 +1,69 @@
+package geo
+
+import "math"
+
+const (
+	// web mercator can't show the poles
+	maxMercatorLat = 85.05112878
+
+	tileSize = 256.0
+
+	MaxZoom = 22
+)
+
+// Cluster is a group of points that are drawn as one marker
+type Cluster struct {
+	Members []int // indexes in the points slice given to ClusterByGrid
+	Center  Point // average position of the members
+	Bounds  BBox
+}
+
+// ClusterByGrid groups points that fall in the same square of cellPx pixels
+// on a web mercator map (the projection of OSM / Google / Leaflet tiles) at the
+// given zoom. the grid is fixed to the world, so a marker doesn't jump between
+// clusters when the user pans the map. clusters are returned in the order of
+// their first member
+func ClusterByGrid(points []Point, zoom int, cellPx float64) []Cluster {
+	scale := tileSize * math.Exp2(float64(zoom)) / cellPx
+
+	type cell struct{ x, y int64 }
+	index := map[cell]int{}
+	var clusters []Cluster
+
+	for i, p := range points {
+		x, y := mercator(p)
+		key := cell{int64(math.Floor(x * scale)), int64(math.Floor(y * scale))}
+
+		n, ok := index[key]
+		if !ok {
+			n = len(clusters)
+			index[key] = n
+			clusters = append(clusters, Cluster{Bounds: BBox{MinLat: p.Lat, MinLng: p.Lng, MaxLat: p.Lat, MaxLng: p.Lng}})
+		}
+
+		cl := &clusters[n]
+		cl.Members = append(cl.Members, i)
+		cl.Center.Lat += p.Lat
+		cl.Center.Lng += p.Lng
+		cl.Bounds.MinLat = math.Min(cl.Bounds.MinLat, p.Lat)
+		cl.Bounds.MinLng = math.Min(cl.Bounds.MinLng, p.Lng)
+		cl.Bounds.MaxLat = math.Max(cl.Bounds.MaxLat, p.Lat)
+		cl.Bounds.MaxLng = math.Max(cl.Bounds.MaxLng, p.Lng)
+	}
+
+	for i := range clusters {
+		n := float64(len(clusters[i].Members))
+		clusters[i].Center.Lat /= n
+		clusters[i].Center.Lng /= n
+	}
+
+	return clusters
+}
+
+// position on the world map, both between 0 and 1
+func mercator(p Point) (float64, float64) {
+	lat := math.Max(-maxMercatorLat, math.Min(maxMercatorLat, p.Lat)) * math.Pi / 180
+	x := (p.Lng + 180) / 360
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2
+	return x, y
+}
@@ -0,0 +1,89 @@
+package geo
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var (
+	bandungA = Point{Lat: -6.9147, Lng: 107.6098}
+	bandungB = Point{Lat: -6.9150, Lng: 107.6102} // ~50 m from A
+	jakarta  = Point{Lat: -6.2088, Lng: 106.8456}
+	london   = Point{Lat: 51.5074, Lng: -0.1278}
+)
+
+func members(clusters []Cluster) [][]int {
+	out := make([][]int, len(clusters))
+	for i, cl := range clusters {
+		out[i] = cl.Members
+	}
+	return out
+}
+
+func TestClusterByGrid(t *testing.T) {
+	points := []Point{bandungA, jakarta, bandungB, london}
+
+	tests := []struct {
+		name string
+		zoom int
+		want [][]int
+	}{
+		// the whole world is 256 px, Indonesia is one cell
+		{"world", 0, [][]int{{0, 1, 2}, {3}}},
+		// city level: Bandung and Jakarta are ~120 km apart
+		{"city", 10, [][]int{{0, 2}, {1}, {3}}},
+		// street level: every cafe alone
+		{"street", 20, [][]int{{0}, {1}, {2}, {3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := members(ClusterByGrid(points, tt.zoom, 60))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("zoom %d: members = %v, want %v", tt.zoom, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterCenterAndBounds(t *testing.T) {
+	clusters := ClusterByGrid([]Point{bandungA, bandungB}, 10, 60)
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters, want 1", len(clusters))
+	}
+	cl := clusters[0]
+
+	wantCenter := Point{Lat: (bandungA.Lat + bandungB.Lat) / 2, Lng: (bandungA.Lng + bandungB.Lng) / 2}
+	if math.Abs(cl.Center.Lat-wantCenter.Lat) > 1e-9 || math.Abs(cl.Center.Lng-wantCenter.Lng) > 1e-9 {
+		t.Errorf("center = %+v, want %+v", cl.Center, wantCenter)
+	}
+
+	wantBounds := BBox{MinLat: bandungB.Lat, MinLng: bandungA.Lng, MaxLat: bandungA.Lat, MaxLng: bandungB.Lng}
+	if cl.Bounds != wantBounds {
+		t.Errorf("bounds = %+v, want %+v", cl.Bounds, wantBounds)
+	}
+}
+
+// the grid is fixed to the world: which points share a cluster doesn't depend
+// on the other points in the request (e.g. after panning the map)
+func TestClusterGridIsStable(t *testing.T) {
+	all := members(ClusterByGrid([]Point{bandungA, bandungB, jakarta}, 8, 60))
+	alone := members(ClusterByGrid([]Point{bandungA, bandungB}, 8, 60))
+
+	if !reflect.DeepEqual(all[0], []int{0, 1}) || !reflect.DeepEqual(alone, [][]int{{0, 1}}) {
+		t.Errorf("with jakarta = %v, without = %v", all, alone)
+	}
+}
+
+func TestClusterPoles(t *testing.T) {
+	// mercator can't show the poles, they are clamped instead of becoming NaN / Inf
+	clusters := ClusterByGrid([]Point{{Lat: 90, Lng: 0}, {Lat: -90, Lng: 0}}, 3, 60)
+	if len(clusters) != 2 {
+		t.Errorf("got %d clusters, want 2", len(clusters))
+	}
+
+	if clusters := ClusterByGrid(nil, 5, 60); len(clusters) != 0 {
+		t.Errorf("no points should give no clusters, got %v", clusters)
+	}
+}
@@ -0,0 +1,107 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// mean earth radius in meters, good enough for walking distances
+const EarthRadius = 6371000.0
+
+// Point is a WGS84 coordinate
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Valid checks that the point is inside the lat/lng range
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// Distance returns the great-circle distance between a and b in meters (haversine)
+func Distance(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BBox is a map viewport. boxes crossing the antimeridian are not supported,
+// MinLng must be <= MaxLng
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// ParseBBox reads "minLng,minLat,maxLng,maxLat" (same order as GeoJSON / most map libraries)
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, errors.New("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+
+	var v [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return BBox{}, errors.New("bbox must contain numbers only")
+		}
+		v[i] = f
+	}
+
+	box := BBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	if !(Point{Lat: box.MinLat, Lng: box.MinLng}).Valid() || !(Point{Lat: box.MaxLat, Lng: box.MaxLng}).Valid() {
+		return BBox{}, errors.New("bbox is outside the lat/lng range")
+	}
+	if box.MinLat > box.MaxLat || box.MinLng > box.MaxLng {
+		return BBox{}, errors.New("bbox min must be smaller than max")
+	}
+
+	return box, nil
+}
+
+// Contains reports whether p is inside the box (edges included)
+func (b BBox) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+// Around returns the smallest box that contains the circle around center.
+// used as a cheap (indexed) prefilter before the exact distance is computed.
+// near a pole or the antimeridian the box spans all longitudes
+func Around(center Point, radius float64) BBox {
+	dLat := radius / EarthRadius * 180 / math.Pi
+
+	box := BBox{
+		MinLat: math.Max(-90, center.Lat-dLat),
+		MaxLat: math.Min(90, center.Lat+dLat),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+
+	// a circle around a pole contains every longitude
+	if center.Lat+dLat >= 90 || center.Lat-dLat <= -90 {
+		return box
+	}
+
+	// longitude degrees get shorter towards the poles
+	dLng := dLat / math.Cos(center.Lat*math.Pi/180)
+
+	// a box can't cross the antimeridian, keep every longitude instead
+	if center.Lng-dLng < -180 || center.Lng+dLng > 180 {
+		return box
+	}
+
+	box.MinLng = center.Lng - dLng
+	box.MaxLng = center.Lng + dLng
+	return box
+}
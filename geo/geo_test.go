@@ -0,0 +1,111 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64 // meters
+		tol  float64
+	}{
+		{"same point", bandungA, bandungA, 0, 0},
+		{"bandung - jakarta", bandungA, jakarta, 115_300, 500},
+		{"jakarta - london", jakarta, london, 11_710_000, 20_000},
+		{"one degree of latitude", Point{0, 0}, Point{1, 0}, 111_195, 1},
+		{"one degree of longitude at the equator", Point{0, 0}, Point{0, 1}, 111_195, 1},
+		{"across the antimeridian", Point{0, 179.9}, Point{0, -179.9}, 22_239, 1},
+		{"pole to pole", Point{90, 0}, Point{-90, 0}, math.Pi * EarthRadius, 1},
+		{"longitude means nothing at the pole", Point{90, 0}, Point{90, 120}, 0, 1e-6},
+		{"antipodes", Point{0, 0}, Point{0, 180}, math.Pi * EarthRadius, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.a, tt.b)
+			if math.IsNaN(got) || math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("Distance = %.1f, want %.1f ± %.1f", got, tt.want, tt.tol)
+			}
+			if back := Distance(tt.b, tt.a); math.Abs(back-got) > 1e-6 {
+				t.Errorf("not symmetric: %.3f vs %.3f", got, back)
+			}
+		})
+	}
+}
+
+func TestParseBBox(t *testing.T) {
+	box, err := ParseBBox(" 107.5, -7.0 ,107.7,-6.8")
+	if err != nil || box != (BBox{MinLng: 107.5, MinLat: -7.0, MaxLng: 107.7, MaxLat: -6.8}) {
+		t.Fatalf("ParseBBox = %+v, %v", box, err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"1,2,3",
+		"a,b,c,d",
+		"0,0,181,1",     // outside the range
+		"0,-91,1,1",     // outside the range
+		"10,0,5,1",      // min > max
+		"170,0,-170,10", // crosses the antimeridian
+	} {
+		if _, err := ParseBBox(bad); err == nil {
+			t.Errorf("ParseBBox(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAround(t *testing.T) {
+	tests := []struct {
+		name   string
+		center Point
+		radius float64
+	}{
+		{"bandung", bandungA, 1000},
+		{"equator", Point{0, 0}, 50_000},
+		{"far north", Point{80, 30}, 50_000},
+		{"near the north pole", Point{89.9, 10}, 50_000},
+		{"south pole", Point{-90, 0}, 1000},
+		{"east of the antimeridian", Point{-16.5, 179.99}, 5000},
+		{"west of the antimeridian", Point{-16.5, -179.99}, 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := Around(tt.center, tt.radius)
+			if !box.Contains(tt.center) {
+				t.Fatalf("box %+v misses the center", box)
+			}
+
+			// every point on the circle must be in the box
+			for deg := 0; deg < 360; deg += 5 {
+				p := destination(tt.center, float64(deg), tt.radius*0.999)
+				if !box.Contains(p) {
+					t.Errorf("box %+v misses %+v at bearing %d", box, p, deg)
+				}
+			}
+		})
+	}
+
+	// small circles away from poles and the antimeridian stay small
+	box := Around(bandungA, 1000)
+	if box.MaxLat-box.MinLat > 0.02 || box.MaxLng-box.MinLng > 0.02 {
+		t.Errorf("box around bandung is too big: %+v", box)
+	}
+}
+
+// point at distance d (meters) from p in the direction of bearing (degrees)
+func destination(p Point, bearing, d float64) Point {
+	lat1 := p.Lat * math.Pi / 180
+	lng1 := p.Lng * math.Pi / 180
+	b := bearing * math.Pi / 180
+	ang := d / EarthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(ang) + math.Cos(lat1)*math.Sin(ang)*math.Cos(b))
+	lng2 := lng1 + math.Atan2(math.Sin(b)*math.Sin(ang)*math.Cos(lat1), math.Cos(ang)-math.Sin(lat1)*math.Sin(lat2))
+
+	lng := math.Mod(lng2*180/math.Pi+540, 360) - 180
+	return Point{Lat: lat2 * 180 / math.Pi, Lng: lng}
+}
@@ -0,0 +1,47 @@
+package geo
+
+// GeoJSON (RFC 7946) types, only what the API needs: point features
+
+type Geometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // [lng, lat], GeoJSON puts longitude first
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	BBox       []float64              `json:"bbox,omitempty"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewPointFeature creates a Point feature, properties can be nil
+func NewPointFeature(id interface{}, p Point, properties map[string]interface{}) Feature {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	return Feature{
+		Type:       "Feature",
+		ID:         id,
+		Geometry:   Geometry{Type: "Point", Coordinates: [2]float64{p.Lng, p.Lat}},
+		Properties: properties,
+	}
+}
+
+// NewFeatureCollection never returns a null "features" list
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// GeoJSON order: [west, south, east, north]
+func (b BBox) GeoJSON() []float64 {
+	return []float64{b.MinLng, b.MinLat, b.MaxLng, b.MaxLat}
+}
@@ -0,0 +1,66 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Fixture answers from a json file instead of calling a real service,
+// for local development, demos and tests. The file maps addresses to results:
+//
+//	{
+//	  "Jl. Keputih No. 1, Surabaya": {
+//	    "point": {"lat": -7.2893, "lng": 112.7975},
+//	    "street": "Jalan Keputih 1", "district": "Sukolilo", "city": "Surabaya", "postal_code": "60111"
+//	  }
+//	}
+//
+// Addresses are compared case-insensitive and without extra spaces.
+// Results can also be set directly in Places (then Path is not read)
+type Fixture struct {
+	Path   string
+	Places map[string]Result
+
+	once    sync.Once
+	loadErr error
+}
+
+func (f *Fixture) Geocode(ctx context.Context, address string) (Result, error) {
+	f.once.Do(f.load)
+	if f.loadErr != nil {
+		return Result{}, f.loadErr
+	}
+
+	result, ok := f.Places[normalizeAddress(address)]
+	if !ok {
+		return Result{}, ErrNotFound
+	}
+	return result, nil
+}
+
+func (f *Fixture) load() {
+	places := f.Places
+	if places == nil {
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			f.loadErr = err
+			return
+		}
+		if err := json.Unmarshal(data, &places); err != nil {
+			f.loadErr = err
+			return
+		}
+	}
+
+	f.Places = make(map[string]Result, len(places))
+	for address, result := range places {
+		f.Places[normalizeAddress(address)] = result
+	}
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.Join(strings.Fields(address), " "))
+}
@@ -0,0 +1,44 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rizqy/cafetify/geo"
+)
+
+func TestFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	os.WriteFile(path, []byte(`{
+		"Jl. Keputih No. 1,  Surabaya": {"point": {"lat": -7.2893, "lng": 112.7975}, "city": "Surabaya"}
+	}`), 0o600)
+
+	keputih := Result{Point: geo.Point{Lat: -7.2893, Lng: 112.7975}, City: "Surabaya"}
+
+	tests := []struct {
+		name     string
+		fixture  *Fixture
+		address  string
+		want     Result
+		wantErr  bool
+		notFound bool
+	}{
+		{"from file", &Fixture{Path: path}, "Jl. Keputih No. 1, Surabaya", keputih, false, false},
+		{"case and spaces are ignored", &Fixture{Path: path}, "  jl. keputih   no. 1, SURABAYA ", keputih, false, false},
+		{"unknown address", &Fixture{Path: path}, "Jl. Braga 1, Bandung", Result{}, true, true},
+		{"places set directly", &Fixture{Places: map[string]Result{"Braga 1": {City: "Bandung"}}}, "braga 1", Result{City: "Bandung"}, false, false},
+		{"missing file", &Fixture{Path: filepath.Join(t.TempDir(), "missing.json")}, "anything", Result{}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fixture.Geocode(context.Background(), tt.address)
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrNotFound) != tt.notFound || got != tt.want {
+				t.Errorf("Geocode(%q) = %+v, %v", tt.address, got, err)
+			}
+		})
+	}
+}
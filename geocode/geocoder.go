@@ -0,0 +1,59 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/rizqy/cafetify/geo"
+)
+
+// ErrNotFound means the geocoder answered, but doesn't know the address.
+// retrying won't help, every other error is treated as temporary
+var ErrNotFound = errors.New("address not found")
+
+// Result is the resolved location of an address
+type Result struct {
+	Point       geo.Point `json:"point"`
+	Street      string    `json:"street"`
+	District    string    `json:"district"`
+	City        string    `json:"city"`
+	PostalCode  string    `json:"postal_code"`
+	DisplayName string    `json:"display_name"`
+}
+
+// Geocoder turns a free text address into coordinates + address parts.
+// Nominatim is used in production, Fixture for local development and tests
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (Result, error)
+}
+
+// FromEnv picks the geocoder from the GEOCODER variable in .env
+//
+//	GEOCODER=nominatim -> GEOCODER_URL (default the public OSM server), GEOCODER_USER_AGENT, GEOCODER_EMAIL
+//	GEOCODER=fixture   -> GEOCODER_FIXTURES (json file, default "geocode_fixtures.json")
+//	anything else      -> nil, cafes are not geocoded
+func FromEnv() Geocoder {
+	switch os.Getenv("GEOCODER") {
+	case "nominatim":
+		return &Nominatim{
+			BaseURL:     envOr("GEOCODER_URL", "https://nominatim.openstreetmap.org"),
+			UserAgent:   envOr("GEOCODER_USER_AGENT", "cafetify/1.0"),
+			Email:       os.Getenv("GEOCODER_EMAIL"),
+			Timeout:     10 * time.Second,
+			MinInterval: time.Second,
+		}
+	case "fixture":
+		return &Fixture{Path: envOr("GEOCODER_FIXTURES", "geocode_fixtures.json")}
+	default:
+		return nil
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,146 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rizqy/cafetify/geo"
+)
+
+// Nominatim talks to a Nominatim compatible /search endpoint (OSM, LocationIQ, self hosted...).
+// the public OSM server requires a real User-Agent and max 1 request per second,
+// MinInterval makes every request wait for its turn, also when called from many goroutines
+type Nominatim struct {
+	BaseURL     string
+	UserAgent   string
+	Email       string // optional, sent so the server admin can contact us
+	Timeout     time.Duration
+	MinInterval time.Duration // time between two requests, 0 = no limit
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next request
+}
+
+// one row of the jsonv2 answer (only the fields we use)
+type nominatimPlace struct {
+	Lat         string            `json:"lat"`
+	Lon         string            `json:"lon"`
+	DisplayName string            `json:"display_name"`
+	Address     map[string]string `json:"address"`
+}
+
+func (n *Nominatim) Geocode(ctx context.Context, address string) (Result, error) {
+	if err := n.wait(ctx); err != nil {
+		return Result{}, err
+	}
+
+	q := url.Values{}
+	q.Set("q", address)
+	q.Set("format", "jsonv2")
+	q.Set("addressdetails", "1")
+	q.Set("limit", "1")
+	if n.Email != "" {
+		q.Set("email", n.Email)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(n.BaseURL, "/")+"/search?"+q.Encode(), nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("User-Agent", n.UserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: n.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("nominatim returned %s", resp.Status)
+	}
+
+	return parseNominatim(resp.Body)
+}
+
+// wait until the request may be sent, the turns are handed out in call order
+func (n *Nominatim) wait(ctx context.Context) error {
+	if n.MinInterval <= 0 {
+		return nil
+	}
+
+	n.mu.Lock()
+	now := time.Now()
+	at := n.next
+	if at.Before(now) {
+		at = now
+	}
+	n.next = at.Add(n.MinInterval)
+	n.mu.Unlock()
+
+	if at.Equal(now) {
+		return nil
+	}
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// read the jsonv2 answer of /search
+func parseNominatim(body io.Reader) (Result, error) {
+	var places []nominatimPlace
+	if err := json.NewDecoder(body).Decode(&places); err != nil {
+		return Result{}, err
+	}
+	if len(places) == 0 {
+		return Result{}, ErrNotFound
+	}
+
+	place := places[0]
+	lat, err1 := strconv.ParseFloat(place.Lat, 64)
+	lng, err2 := strconv.ParseFloat(place.Lon, 64)
+	if err1 != nil || err2 != nil {
+		return Result{}, fmt.Errorf("nominatim returned invalid coordinates %q, %q", place.Lat, place.Lon)
+	}
+
+	a := place.Address
+	street := first(a, "road", "pedestrian", "footway")
+	if nr := a["house_number"]; nr != "" && street != "" {
+		street += " " + nr
+	}
+
+	return Result{
+		Point:       geo.Point{Lat: lat, Lng: lng},
+		Street:      street,
+		District:    first(a, "suburb", "city_district", "district", "village", "quarter"),
+		City:        first(a, "city", "town", "municipality", "county"),
+		PostalCode:  a["postcode"],
+		DisplayName: place.DisplayName,
+	}, nil
+}
+
+// Nominatim uses different keys depending on the country, take the first one that is set
+func first(address map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if v := address[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
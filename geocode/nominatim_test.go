@@ -0,0 +1,146 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rizqy/cafetify/geo"
+)
+
+func TestParseNominatim(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Result
+		wantErr error
+		anyErr  bool // some error, but not ErrNotFound
+	}{
+		{
+			name: "city address",
+			body: `[{"lat":"-7.2893","lon":"112.7975","display_name":"Jalan Keputih, Sukolilo, Surabaya",
+				"address":{"road":"Jalan Keputih","house_number":"1","suburb":"Sukolilo","city":"Surabaya","postcode":"60111"}}]`,
+			want: Result{
+				Point:       geo.Point{Lat: -7.2893, Lng: 112.7975},
+				Street:      "Jalan Keputih 1",
+				District:    "Sukolilo",
+				City:        "Surabaya",
+				PostalCode:  "60111",
+				DisplayName: "Jalan Keputih, Sukolilo, Surabaya",
+			},
+		},
+		{
+			name: "other keys for the parts, no house number",
+			body: `[{"lat":"-8.65","lon":"115.13","address":{"pedestrian":"Gang Bunga","village":"Canggu","town":"Kuta Utara"}}]`,
+			want: Result{Point: geo.Point{Lat: -8.65, Lng: 115.13}, Street: "Gang Bunga", District: "Canggu", City: "Kuta Utara"},
+		},
+		{
+			name: "house number without street is dropped",
+			body: `[{"lat":"1","lon":"2","address":{"house_number":"5"}}]`,
+			want: Result{Point: geo.Point{Lat: 1, Lng: 2}},
+		},
+		{
+			name: "only the first place is used",
+			body: `[{"lat":"1","lon":"2"},{"lat":"3","lon":"4"}]`,
+			want: Result{Point: geo.Point{Lat: 1, Lng: 2}},
+		},
+		{name: "no places", body: `[]`, wantErr: ErrNotFound},
+		{name: "invalid coordinates", body: `[{"lat":"north","lon":"2"}]`, anyErr: true},
+		{name: "not json", body: `<html>rate limited</html>`, anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNominatim(strings.NewReader(tt.body))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil || errors.Is(err, ErrNotFound) {
+					t.Errorf("err = %v, want a temporary error", err)
+				}
+			case err != nil || got != tt.want:
+				t.Errorf("parseNominatim = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNominatimRequest(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if r.URL.Query().Get("q") == "down" {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"lat":"-6.9","lon":"107.6"}]`))
+	}))
+	defer srv.Close()
+
+	n := &Nominatim{BaseURL: srv.URL + "/", UserAgent: "cafetify-test", Email: "admin@example.com", Timeout: time.Second}
+
+	result, err := n.Geocode(context.Background(), "Jl. Braga 1, Bandung")
+	if err != nil || result.Point != (geo.Point{Lat: -6.9, Lng: 107.6}) {
+		t.Fatalf("Geocode = %+v, %v", result, err)
+	}
+
+	q := got.URL.Query()
+	if got.URL.Path != "/search" || q.Get("q") != "Jl. Braga 1, Bandung" || q.Get("format") != "jsonv2" ||
+		q.Get("limit") != "1" || q.Get("email") != "admin@example.com" || got.UserAgent() != "cafetify-test" {
+		t.Errorf("unexpected request %s (User-Agent %q)", got.URL, got.UserAgent())
+	}
+
+	// server errors are temporary, not "not found"
+	if _, err := n.Geocode(context.Background(), "down"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("503 = %v, want a temporary error", err)
+	}
+}
+
+func TestNominatimMinInterval(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	interval := 50 * time.Millisecond
+	n := &Nominatim{BaseURL: srv.URL, Timeout: time.Second, MinInterval: interval}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.Geocode(context.Background(), "somewhere")
+		}()
+	}
+	wg.Wait()
+
+	if len(times) != 4 {
+		t.Fatalf("%d requests, want 4", len(times))
+	}
+	if total := times[3].Sub(times[0]); total < 3*interval-5*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least %v", total, 3*interval)
+	}
+
+	// a canceled request gives up its wait instead of blocking the job
+	ctx, cancel := context.WithCancel(context.Background())
+	n.MinInterval = time.Hour
+	n.Geocode(ctx, "first")
+	cancel()
+	if _, err := n.Geocode(ctx, "second"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -17,7 +17,8 @@ func ConnectToDB() {
 	dsn := os.Getenv("DB_URL")
 
 	// Open connection to MySQL using GORM
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError -> unique violations come back as gorm.ErrDuplicatedKey
+	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 
 	// Error Checking: If connection fails, terminate the application (Panic)
 	if err != nil {
@@ -0,0 +1,10 @@
+package initializers
+
+import "github.com/rizqy/cafetify/geocode"
+
+// Global geocoder, nil when GEOCODER is not set (cafes are then not geocoded)
+var Geocoder geocode.Geocoder
+
+func SetupGeocoder() {
+	Geocoder = geocode.FromEnv()
+}
@@ -0,0 +1,32 @@
+package initializers
+
+import (
+	"time"
+
+	"github.com/rizqy/cafetify/ratelimit"
+)
+
+// Global limiters for /login, same idea as DB
+var (
+	// failed logins per account (email)
+	AccountLimiter ratelimit.Limiter
+
+	// failed logins per IP, more attempts allowed because of shared networks (campus wifi)
+	IPLimiter ratelimit.Limiter
+)
+
+func SetupLimiters() {
+	AccountLimiter = ratelimit.NewMemoryLimiter(ratelimit.Policy{
+		FreeAttempts: 5,
+		BaseLockout:  30 * time.Second,
+		MaxLockout:   time.Hour,
+		Window:       time.Hour,
+	})
+
+	IPLimiter = ratelimit.NewMemoryLimiter(ratelimit.Policy{
+		FreeAttempts: 20,
+		BaseLockout:  time.Minute,
+		MaxLockout:   time.Hour,
+		Window:       time.Hour,
+	})
+}
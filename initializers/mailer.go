@@ -0,0 +1,11 @@
+package initializers
+
+import "github.com/rizqy/cafetify/mailer"
+
+// Global mailer, same idea as DB
+var Mailer mailer.Mailer
+
+func SetupMailer() {
+	// choose smtp / file / log from .env
+	Mailer = mailer.FromEnv()
+}
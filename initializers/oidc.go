@@ -0,0 +1,10 @@
+package initializers
+
+import "github.com/rizqy/cafetify/oidc"
+
+// Global OIDC providers (social login), key = provider name in the URL
+var OIDCProviders map[string]*oidc.Provider
+
+func SetupOIDC() {
+	OIDCProviders = oidc.ProvidersFromEnv()
+}
@@ -0,0 +1,25 @@
+package initializers
+
+import (
+	"os"
+	"strings"
+)
+
+// TrustedProxies reads TRUSTED_PROXIES from .env: comma separated IPs or CIDRs
+// of the reverse proxies in front of the server, e.g.
+//
+//	TRUSTED_PROXIES=127.0.0.1,10.0.0.0/8
+//
+// only requests coming from these addresses may set X-Forwarded-For / X-Real-IP.
+// empty = no proxy, c.ClientIP() is always the address of the connection.
+// trusting every peer would let a client pick a new IP for the login limiter
+// on every attempt
+func TrustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
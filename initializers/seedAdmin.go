@@ -0,0 +1,20 @@
+package initializers
+
+import (
+	"log"
+	"os"
+)
+
+// SeedAdmin gives the admin role to the user with ADMIN_EMAIL (if set).
+// the first admin can't be created from the API, so it comes from .env
+func SeedAdmin() {
+	email := os.Getenv("ADMIN_EMAIL")
+	if email == "" {
+		return
+	}
+
+	result := DB.Table("users").Where("email = ?", email).Update("role", "admin")
+	if result.Error != nil {
+		log.Println("Failed to seed admin:", result.Error)
+	}
+}
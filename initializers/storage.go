@@ -0,0 +1,11 @@
+package initializers
+
+import "github.com/rizqy/cafetify/storage"
+
+// Global file storage for uploaded photos
+var Storage storage.Storage
+
+func SetupStorage() {
+	// local folder or S3 from .env
+	Storage = storage.FromEnv()
+}
@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+// StartAccountPurge runs PurgeDeletedAccounts every interval in the background
+func StartAccountPurge(interval time.Duration) {
+	go func() {
+		for {
+			if err := PurgeDeletedAccounts(); err != nil {
+				log.Println("account purge failed:", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// PurgeDeletedAccounts removes the data of every account whose grace period is over
+func PurgeDeletedAccounts() error {
+	var users []models.User
+	if err := initializers.DB.
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		// cafes whose stats change when the ratings of the user are gone
+		var ratedCafeIDs []uint
+		if err := initializers.DB.Model(&models.PersonalRating{}).
+			Where("user_id = ?", user.ID).
+			Distinct().Pluck("cafe_id", &ratedCafeIDs).Error; err != nil {
+			log.Printf("failed to purge user %d: %v", user.ID, err)
+			continue
+		}
+
+		var photoKeys []string
+		if err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			photoKeys, err = purgeAccount(tx, user)
+			return err
+		}); err != nil {
+			log.Printf("failed to purge user %d: %v", user.ID, err)
+			continue
+		}
+
+		// files are removed after the commit, a failed delete only leaves an unused file
+		deleteStoredFiles(photoKeys)
+		for _, cafeID := range ratedCafeIDs {
+			if _, err := models.RefreshRatingStats(initializers.DB, cafeID); err != nil {
+				log.Printf("failed to refresh rating stats of cafe %d: %v", cafeID, err)
+			}
+		}
+		log.Printf("purged account of user %d", user.ID)
+	}
+
+	return nil
+}
+
+// Deletion policy:
+//   - the user's own ratings (with personal notes) and photos are deleted
+//   - cafes the user created that nobody else rated are deleted
+//   - cafes that other users rated are shared data, they stay and keep
+//     pointing at the anonymized user row
+//   - sessions, tokens, API keys, 2FA codes, linked identities and email change
+//     requests (old + new address) are deleted
+//   - audit logs stay, but the email in the login audit is removed
+//   - the user row is anonymized (email, name, password, 2FA) and soft deleted,
+//     it can't login anymore and the email can be registered again
+//
+// returns the storage keys of the deleted photos
+func purgeAccount(tx *gorm.DB, user models.User) ([]string, error) {
+	// 1. ratings of the user
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.PersonalRating{}).Error; err != nil {
+		return nil, err
+	}
+
+	// 2. cafes of the user without ratings from others
+	var cafeIDs []uint
+	if err := tx.Model(&models.Cafe{}).Unscoped().
+		Where("user_id = ?", user.ID).
+		Where("NOT EXISTS (SELECT 1 FROM personal_ratings pr WHERE pr.cafe_id = cafes.id AND pr.user_id <> ?)", user.ID).
+		Pluck("id", &cafeIDs).Error; err != nil {
+		return nil, err
+	}
+
+	// photos of the user + every photo of the cafes that are deleted
+	photoQuery := tx.Unscoped().Model(&models.Photo{}).Where("user_id = ?", user.ID)
+	if len(cafeIDs) > 0 {
+		photoQuery = photoQuery.Or("cafe_id IN ?", cafeIDs)
+	}
+	var photos []models.Photo
+	if err := photoQuery.Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	var photoKeys []string
+	var photoIDs []uint
+	for _, p := range photos {
+		photoKeys = append(photoKeys, p.Key, p.ThumbKey)
+		photoIDs = append(photoIDs, p.ID)
+	}
+	if len(photoIDs) > 0 {
+		if err := tx.Unscoped().Delete(&models.Photo{}, photoIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cafeIDs) > 0 {
+		if err := tx.Exec("DELETE FROM cafe_tags WHERE cafe_id IN ?", cafeIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, model := range []interface{}{
+			&models.PersonalRating{},
+			&models.OpeningHour{},
+			&models.OpeningException{},
+			&models.MenuItem{},
+			&models.CafeRatingStats{},
+		} {
+			if err := tx.Unscoped().Where("cafe_id IN ?", cafeIDs).Delete(model).Error; err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Unscoped().Delete(&models.Cafe{}, cafeIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. login data
+	for _, model := range []interface{}{
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.APIKey{},
+		&models.RecoveryCode{},
+		&models.LinkedIdentity{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.EmailChangeRequest{},
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. audit logs stay, without the email
+	if err := tx.Model(&models.LoginAudit{}).Where("user_id = ?", user.ID).Update("email", "").Error; err != nil {
+		return nil, err
+	}
+
+	// 5. anonymize + soft delete the user row
+	now := time.Now()
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"email":                 fmt.Sprintf("deleted-%d@deleted.invalid", user.ID),
+		"username":              "Deleted user",
+		"password":              "",
+		"role":                  models.RoleUser,
+		"totp_secret":           "",
+		"totp_enabled_at":       nil,
+		"email_verified_at":     nil,
+		"deletion_scheduled_at": nil,
+		"tokens_revoked_at":     now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return photoKeys, tx.Delete(&models.User{}, user.ID).Error
+}
+
+func deleteStoredFiles(keys []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, key := range keys {
+		if err := initializers.Storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete %s: %v", key, err)
+		}
+	}
+}
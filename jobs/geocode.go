@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rizqy/cafetify/geocode"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+	"gorm.io/gorm"
+)
+
+const (
+	geocodeBatchSize   = 20
+	geocodeMaxAttempts = 5
+	geocodeTimeout     = 15 * time.Second
+
+	// first retry after 1 minute, then 2, 4, 8...
+	geocodeRetryBase = time.Minute
+)
+
+// wakes the worker up when a cafe is queued, so it doesn't wait for the next tick
+var geocodeWake = make(chan struct{}, 1)
+
+// StartGeocoder resolves the queued cafe addresses in the background.
+// the queue is the cafes table itself (geocode_status = pending), so nothing is
+// lost on restart. does nothing when no geocoder is configured
+func StartGeocoder(interval time.Duration) {
+	if initializers.Geocoder == nil {
+		return
+	}
+
+	go func() {
+		for {
+			if err := GeocodePendingCafes(); err != nil {
+				log.Println("geocoding failed:", err)
+			}
+
+			select {
+			case <-geocodeWake:
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// QueueGeocode marks the cafe for geocoding, use it inside the transaction that
+// saves the address and call WakeGeocoder after the commit
+func QueueGeocode(tx *gorm.DB, cafe *models.Cafe) error {
+	if initializers.Geocoder == nil {
+		return nil
+	}
+
+	return tx.Model(cafe).Updates(map[string]interface{}{
+		"geocode_status":   models.GeocodePending,
+		"geocode_attempts": 0,
+		"geocode_next_at":  time.Now(),
+		"geocode_error":    "",
+	}).Error
+}
+
+// WakeGeocoder tells the worker there is new work (never blocks)
+func WakeGeocoder() {
+	select {
+	case geocodeWake <- struct{}{}:
+	default:
+	}
+}
+
+// GeocodePendingCafes geocodes every queued cafe that is due, one request at a time
+func GeocodePendingCafes() error {
+	for {
+		var cafes []models.Cafe
+		if err := initializers.DB.
+			Where("geocode_status = ? AND geocode_next_at <= ?", models.GeocodePending, time.Now()).
+			Order("geocode_next_at").
+			Limit(geocodeBatchSize).
+			Find(&cafes).Error; err != nil {
+			return err
+		}
+
+		for _, cafe := range cafes {
+			if err := geocodeCafe(cafe); err != nil {
+				log.Printf("failed to save geocode result of cafe %d: %v", cafe.ID, err)
+			}
+		}
+
+		if len(cafes) < geocodeBatchSize {
+			return nil
+		}
+	}
+}
+
+func geocodeCafe(cafe models.Cafe) error {
+	ctx, cancel := context.WithTimeout(context.Background(), geocodeTimeout)
+	defer cancel()
+
+	result, err := initializers.Geocoder.Geocode(ctx, cafe.Address)
+	if err != nil && !errors.Is(err, geocode.ErrNotFound) {
+		log.Printf("geocoding cafe %d failed (attempt %d): %v", cafe.ID, cafe.GeocodeAttempts+1, err)
+	}
+
+	// the address may have changed while we were waiting for the geocoder,
+	// then this result is old and the new address is already queued again
+	return initializers.DB.Model(&models.Cafe{}).
+		Where("id = ? AND address = ? AND geocode_status = ?", cafe.ID, cafe.Address, models.GeocodePending).
+		Updates(geocodeUpdates(cafe, result, err, time.Now())).Error
+}
+
+// the columns to save after one geocoder answer:
+// found -> done, unknown address -> not_found, other errors -> retry later or failed
+func geocodeUpdates(cafe models.Cafe, result geocode.Result, err error, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{}
+	switch {
+	case err == nil:
+		updates["geocode_status"] = models.GeocodeDone
+		updates["geocode_error"] = ""
+		updates["street"] = result.Street
+		updates["district"] = result.District
+		updates["city"] = result.City
+		updates["postal_code"] = result.PostalCode
+
+		// coordinates typed by the user are more exact than the geocoder
+		if cafe.LocationSource != models.LocationManual {
+			updates["latitude"] = result.Point.Lat
+			updates["longitude"] = result.Point.Lng
+			updates["location_source"] = models.LocationGeocoder
+		}
+
+	case errors.Is(err, geocode.ErrNotFound):
+		updates["geocode_status"] = models.GeocodeNotFound
+		updates["geocode_error"] = ""
+
+	default:
+		attempts := cafe.GeocodeAttempts + 1
+		updates["geocode_attempts"] = attempts
+		updates["geocode_error"] = truncate(err.Error(), 255)
+
+		if attempts >= geocodeMaxAttempts {
+			updates["geocode_status"] = models.GeocodeFailed
+		} else {
+			updates["geocode_next_at"] = now.Add(geocodeRetryBase << (attempts - 1))
+		}
+	}
+
+	return updates
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
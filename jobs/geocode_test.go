@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rizqy/cafetify/geo"
+	"github.com/rizqy/cafetify/geocode"
+	"github.com/rizqy/cafetify/models"
+)
+
+func TestGeocodeUpdates(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	found := geocode.Result{
+		Point:      geo.Point{Lat: -6.9, Lng: 107.6},
+		Street:     "Jalan Braga 1",
+		District:   "Sumur Bandung",
+		City:       "Bandung",
+		PostalCode: "40111",
+	}
+	timeout := errors.New("context deadline exceeded")
+
+	tests := []struct {
+		name   string
+		cafe   models.Cafe
+		result geocode.Result
+		err    error
+		want   map[string]interface{}
+	}{
+		{
+			name:   "found",
+			cafe:   models.Cafe{GeocodeStatus: models.GeocodePending},
+			result: found,
+			want: map[string]interface{}{
+				"geocode_status": models.GeocodeDone, "geocode_error": "",
+				"street": "Jalan Braga 1", "district": "Sumur Bandung", "city": "Bandung", "postal_code": "40111",
+				"latitude": -6.9, "longitude": 107.6, "location_source": models.LocationGeocoder,
+			},
+		},
+		{
+			name:   "found, manual coordinates are kept",
+			cafe:   models.Cafe{GeocodeStatus: models.GeocodePending, LocationSource: models.LocationManual},
+			result: found,
+			want: map[string]interface{}{
+				"geocode_status": models.GeocodeDone, "geocode_error": "",
+				"street": "Jalan Braga 1", "district": "Sumur Bandung", "city": "Bandung", "postal_code": "40111",
+			},
+		},
+		{
+			name: "unknown address is not retried",
+			cafe: models.Cafe{GeocodeStatus: models.GeocodePending, GeocodeAttempts: 2},
+			err:  geocode.ErrNotFound,
+			want: map[string]interface{}{"geocode_status": models.GeocodeNotFound, "geocode_error": ""},
+		},
+		{
+			name: "first error stays pending, retry after 1 minute",
+			cafe: models.Cafe{GeocodeStatus: models.GeocodePending},
+			err:  timeout,
+			want: map[string]interface{}{
+				"geocode_attempts": 1, "geocode_error": timeout.Error(), "geocode_next_at": now.Add(time.Minute),
+			},
+		},
+		{
+			name: "backoff doubles",
+			cafe: models.Cafe{GeocodeStatus: models.GeocodePending, GeocodeAttempts: 3},
+			err:  timeout,
+			want: map[string]interface{}{
+				"geocode_attempts": 4, "geocode_error": timeout.Error(), "geocode_next_at": now.Add(8 * time.Minute),
+			},
+		},
+		{
+			name: "last attempt marks the cafe failed",
+			cafe: models.Cafe{GeocodeStatus: models.GeocodePending, GeocodeAttempts: geocodeMaxAttempts - 1},
+			err:  timeout,
+			want: map[string]interface{}{
+				"geocode_attempts": geocodeMaxAttempts, "geocode_error": timeout.Error(), "geocode_status": models.GeocodeFailed,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := geocodeUpdates(tt.cafe, tt.result, tt.err, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("geocodeUpdates =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeocodeUpdatesTruncatesError(t *testing.T) {
+	long := errors.New(string(make([]byte, 1000)))
+	got := geocodeUpdates(models.Cafe{}, geocode.Result{}, long, time.Now())
+	if n := len(got["geocode_error"].(string)); n != 255 {
+		t.Errorf("error saved with %d bytes, the column has 255", n)
+	}
+}
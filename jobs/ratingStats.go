@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"log"
+
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+// StartRatingStatsBackfill computes the missing rating stats once in the background,
+// for cafes created before the stats existed
+func StartRatingStatsBackfill() {
+	go func() {
+		if err := BackfillRatingStats(); err != nil {
+			log.Println("rating stats backfill failed:", err)
+		}
+	}()
+}
+
+// BackfillRatingStats refreshes the stats of every cafe that has no stats row yet
+func BackfillRatingStats() error {
+	var cafeIDs []uint
+	if err := initializers.DB.Model(&models.Cafe{}).
+		Where("NOT EXISTS (SELECT 1 FROM cafe_rating_stats s WHERE s.cafe_id = cafes.id)").
+		Pluck("id", &cafeIDs).Error; err != nil {
+		return err
+	}
+
+	for _, id := range cafeIDs {
+		if _, err := models.RefreshRatingStats(initializers.DB, id); err != nil {
+			return err
+		}
+	}
+
+	if len(cafeIDs) > 0 {
+		log.Printf("computed rating stats of %d cafes", len(cafeIDs))
+	}
+	return nil
+}
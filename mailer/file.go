@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+var fileCounter atomic.Uint64
+
+// FileMailer writes every email as an .eml file in Dir,
+// so links in the email can be opened locally
+type FileMailer struct {
+	Dir string
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	raw, err := buildMessage("no-reply@cafetify.local", msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%d.eml", time.Now().UnixNano(), fileCounter.Add(1))
+	return os.WriteFile(filepath.Join(m.Dir, name), raw, 0o644)
+}
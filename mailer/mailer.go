@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"log"
+	"os"
+)
+
+// Message is one plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. There is an SMTP implementation for production and
+// log / file implementations for local development and tests.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromEnv picks the mailer from the MAILER variable in .env
+//
+//	MAILER=smtp -> SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, MAIL_FROM
+//	MAILER=file -> MAIL_DIR (default "mails")
+//	anything else -> just print the email to the log
+func FromEnv() Mailer {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     envOr("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     envOr("MAIL_FROM", "no-reply@cafetify.local"),
+		}
+	case "file":
+		return &FileMailer{Dir: envOr("MAIL_DIR", "mails")}
+	default:
+		return &LogMailer{}
+	}
+}
+
+// LogMailer only prints the email, for development
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("[mailer] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	raw, err := buildMessage("no-reply@cafetify.local", Message{
+		To:      "budi@example.com",
+		Subject: "Verifikasi email",
+		Body:    "Halo Budi,\nklik link ini.\r\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "From: no-reply@cafetify.local\r\n" +
+		"To: budi@example.com\r\n" +
+		"Subject: Verifikasi email\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"Halo Budi,\r\nklik link ini.\r\n"
+	if string(raw) != want {
+		t.Errorf("message =\n%q\nwant\n%q", raw, want)
+	}
+
+	// non-ASCII subjects are encoded instead of sent raw
+	raw, _ = buildMessage("no-reply@cafetify.local", Message{To: "budi@example.com", Subject: "Kopi ☕"})
+	if !strings.Contains(string(raw), "Subject: =?UTF-8?q?Kopi_=E2=98=95?=\r\n") {
+		t.Errorf("subject not encoded:\n%s", raw)
+	}
+}
+
+func TestBuildMessageRejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		msg  Message
+	}{
+		{"subject with CRLF", "a@b.c", Message{To: "budi@example.com", Subject: "Hi\r\nBcc: victim@example.com"}},
+		{"subject with LF", "a@b.c", Message{To: "budi@example.com", Subject: "Hi\nBcc: victim@example.com"}},
+		{"subject with CR", "a@b.c", Message{To: "budi@example.com", Subject: "Hi\rBcc: victim@example.com"}},
+		{"recipient with CRLF", "a@b.c", Message{To: "budi@example.com\r\nBcc: victim@example.com", Subject: "Hi"}},
+		{"from with LF", "a@b.c\nBcc: x@y.z", Message{To: "budi@example.com", Subject: "Hi"}},
+		{"recipient is not an address", "a@b.c", Message{To: "budi", Subject: "Hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if raw, err := buildMessage(tt.from, tt.msg); err == nil {
+				t.Errorf("buildMessage accepted it:\n%s", raw)
+			}
+		})
+	}
+}
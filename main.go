@@ -1,31 +1,53 @@
 package main
 
 import (
+	"log"
 	"time"
 
 	"github.com/gin-contrib/cors" // di terminal run dulu "go get github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
 	"github.com/rizqy/cafetify/controllers"
 	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/jobs"
 	"github.com/rizqy/cafetify/middleware"
 	"github.com/rizqy/cafetify/models"
+	"github.com/rizqy/cafetify/storage"
 )
 
 func init() {
 	initializers.LoadEnvVariables()
+	auth.LoadSigningKeys()
+	auth.LoadBreachedPasswords()
 	initializers.ConnectToDB()
-	initializers.DB.AutoMigrate(&models.User{}, &models.Cafe{}, &models.PersonalRating{}, &models.Tag{})
+	initializers.SetupMailer()
+	initializers.SetupLimiters()
+	initializers.SetupOIDC()
+	initializers.SetupGeocoder()
+	initializers.SetupStorage()
+	initializers.DB.AutoMigrate(&models.User{}, &models.Cafe{}, &models.PersonalRating{}, &models.Tag{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Session{}, &models.PasswordResetToken{}, &models.EmailVerificationToken{}, &models.RecoveryCode{}, &models.ModerationLog{}, &models.APIKey{}, &models.LoginAudit{}, &models.LinkedIdentity{}, &models.OIDCLoginState{}, &models.EmailChangeRequest{}, &models.OpeningHour{}, &models.OpeningException{}, &models.Photo{}, &models.MenuItem{}, &models.CafeRatingStats{})
+	initializers.SeedAdmin()
+
+	// remove accounts whose deletion grace period is over
+	jobs.StartAccountPurge(time.Hour)
+	jobs.StartGeocoder(time.Minute)
+	jobs.StartRatingStatsBackfill()
 }
 
 func main() {
 	r := gin.Default()
 
+	// X-Forwarded-For is only read from our own proxies (see initializers/proxy.go)
+	if err := r.SetTrustedProxies(initializers.TrustedProxies()); err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES: ", err)
+	}
+
 	// --- CORS CONFIG ---
 	// biar React (:5173) bisa connect sm Go (:8080)
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // sesuain sm port React kamu
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "X-CSRF-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true, // harus true biar cookie bisa dikirim
 		MaxAge:           12 * time.Hour,
@@ -40,25 +62,108 @@ func main() {
 
 	r.POST("/register", controllers.Register)
 	r.POST("/login", controllers.Login)
+	r.POST("/login/mfa", controllers.LoginMFA)
+	r.POST("/refresh", controllers.Refresh)
+	r.POST("/logout", controllers.Logout)
+	r.POST("/forgot-password", controllers.ForgotPassword)
+	r.POST("/reset-password", controllers.ResetPassword)
+	r.GET("/verify-email", controllers.VerifyEmail)
+	r.GET("/confirm-email-change", controllers.ConfirmEmailChange)
+	r.GET("/undo-email-change", controllers.UndoEmailChange)
+
+	// social login (OpenID Connect, providers from OIDC_PROVIDERS)
+	r.GET("/auth/oidc/:provider/login", controllers.OIDCLogin)
+	r.GET("/auth/oidc/:provider/callback", controllers.OIDCCallback)
 	r.GET("/cafes", controllers.GetAllCafes)
+	r.GET("/cafes/nearby", controllers.GetNearbyCafes)
+	r.GET("/cafes/top", controllers.GetTopCafes)
+	r.GET("/cafes.geojson", controllers.GetCafesGeoJSON)
+	r.GET("/cafes/:id/hours", controllers.GetOpeningHours)
+	r.GET("/cafes/:id/menu", controllers.GetMenu)
 	r.GET("/tags", controllers.GetAllTags)
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
+
+	// uploaded photos, only when they are stored on this server
+	if local, ok := initializers.Storage.(*storage.LocalStorage); ok {
+		r.Static("/uploads", local.Dir)
+	}
 
 	// ---------- route protected
 
 	protected := r.Group("/protected")
-	protected.Use(middleware.RequireAuth)
+	protected.Use(middleware.RequireAuth, middleware.RequireCSRF)
 	{
+		// these routes can also be used with a personal API key (X-API-Key),
+		// RequireScope checks the scope of the key (JWT logins have full access)
+		read := middleware.RequireScope(auth.ScopeRead)
+		writeCafes := middleware.RequireScope(auth.ScopeWriteCafes)
+		writeRatings := middleware.RequireScope(auth.ScopeWriteRatings)
+
 		//route for cafe
-		protected.POST("/cafes", controllers.CreateCafe)
-		protected.GET("/cafes", controllers.GetAllCafes)
-		protected.PUT("/cafes/:id", controllers.UpdateCafe)
-		protected.DELETE("/cafes/:id", controllers.DeleteCafe)
-		protected.POST("/cafes/:id/rate", controllers.RateCafe)
+		// creating data can be blocked for unverified emails (EMAIL_VERIFICATION_POLICY)
+		protected.POST("/cafes", writeCafes, middleware.RequireVerifiedEmail, controllers.CreateCafe)
+		protected.GET("/cafes", read, controllers.GetAllCafes)
+		protected.GET("/cafes/nearby", read, controllers.GetNearbyCafes)
+		protected.GET("/cafes/top", read, controllers.GetTopCafes)
+		protected.GET("/cafes.geojson", read, controllers.GetCafesGeoJSON)
+		protected.PUT("/cafes/:id", writeCafes, controllers.UpdateCafe)
+		protected.DELETE("/cafes/:id", writeCafes, controllers.DeleteCafe)
+		protected.PUT("/cafes/:id/hours", writeCafes, controllers.UpdateOpeningHours)
+		protected.POST("/cafes/:id/photos", writeCafes, middleware.RequireVerifiedEmail, controllers.UploadCafePhoto)
+		protected.GET("/cafes/:id/menu", read, controllers.GetMenu)
+		protected.POST("/cafes/:id/menu", writeCafes, controllers.CreateMenuItem)
+		protected.PUT("/cafes/:id/menu/:item_id", writeCafes, controllers.UpdateMenuItem)
+		protected.DELETE("/cafes/:id/menu/:item_id", writeCafes, controllers.DeleteMenuItem)
+		protected.DELETE("/photos/:id", writeCafes, controllers.DeletePhoto)
+		protected.POST("/cafes/:id/rate", writeRatings, middleware.RequireVerifiedEmail, controllers.RateCafe)
+
+		// route for rating (owner or moderator)
+		protected.PUT("/ratings/:id", writeRatings, controllers.UpdateRating)
+		protected.DELETE("/ratings/:id", writeRatings, controllers.DeleteRating)
+		protected.POST("/ratings/:id/photos", writeRatings, middleware.RequireVerifiedEmail, controllers.UploadRatingPhoto)
+
+		protected.GET("/profile", read, controllers.GetProfile)
+	}
+
+	// account routes need a real login, API keys are rejected here
+	account := protected.Group("", middleware.RequireSession)
+	{
+		// route for tag (moderator only, tags are shared by every cafe)
+		account.PUT("/tags/:id", middleware.RequirePermission(auth.PermModerateContent), controllers.UpdateTag)
+		account.DELETE("/tags/:id", middleware.RequirePermission(auth.PermModerateContent), controllers.DeleteTag)
+
+		// route for moderation / admin
+		account.GET("/moderation-logs", middleware.RequirePermission(auth.PermReadAuditLog), controllers.GetModerationLogs)
+		account.GET("/admin/login-audit", middleware.RequirePermission(auth.PermReadAuditLog), controllers.GetLoginAudit)
+		account.PUT("/admin/users/:id/role", middleware.RequirePermission(auth.PermManageRoles), controllers.UpdateUserRole)
 
 		// route for profile
-		protected.GET("/profile", controllers.GetProfile)
-		protected.PUT("/profile", controllers.UpdateProfile)
-		protected.PUT("/change-password", controllers.ChangePassword)
+		account.PUT("/profile", controllers.UpdateProfile)
+		account.PUT("/change-password", controllers.ChangePassword)
+		account.POST("/logout-all", controllers.LogoutAll)
+		account.GET("/login-history", controllers.GetLoginHistory)
+		account.POST("/resend-verification", controllers.ResendVerification)
+
+		// route for 2FA
+		account.POST("/2fa/enroll", controllers.EnrollTOTP)
+		account.POST("/2fa/confirm", controllers.ConfirmTOTP)
+		account.POST("/2fa/disable", controllers.DisableTOTP)
+
+		account.GET("/identities", controllers.GetIdentities)
+
+		// route for account export / deletion
+		account.GET("/account/export", controllers.ExportAccount)
+		account.DELETE("/account", controllers.DeleteAccount)
+		account.POST("/account/cancel-deletion", controllers.CancelAccountDeletion)
+
+		// route for sessions (logged in devices)
+		account.GET("/sessions", controllers.GetSessions)
+		account.DELETE("/sessions/:id", controllers.DeleteSession)
+
+		// route for personal API keys
+		account.POST("/api-keys", controllers.CreateAPIKey)
+		account.GET("/api-keys", controllers.GetAPIKeys)
+		account.DELETE("/api-keys/:id", controllers.DeleteAPIKey)
 	}
 
 	r.Run()
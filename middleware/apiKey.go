@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/initializers"
+	"github.com/rizqy/cafetify/models"
+)
+
+// authenticate with a personal API key (X-API-Key header), called by RequireAuth
+func requireAPIKey(c *gin.Context, key string) {
+	var apiKey models.APIKey
+	initializers.DB.First(&apiKey, "key_hash = ?", auth.HashToken(key))
+
+	if apiKey.ID == 0 || apiKey.RevokedAt != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key"})
+		return
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API Key Expired"})
+		return
+	}
+
+	var user models.User
+	initializers.DB.First(&user, apiKey.UserID)
+
+	if user.ID == 0 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	// update last used, but not on every request (max once per minute)
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute {
+		initializers.DB.Model(&apiKey).Update("last_used_at", now)
+	}
+
+	// save user + key to context
+	c.Set("user", user)
+	c.Set("api_key", apiKey)
+	c.Set("auth_source", "api_key")
+
+	c.Next()
+}
+
+// RequireScope checks the scope of the API key. logins with a JWT
+// (cookie / bearer) have full access and are always allowed
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyContext, usingKey := c.Get("api_key")
+		if !usingKey {
+			c.Next()
+			return
+		}
+
+		if !auth.HasScope(keyContext.(models.APIKey).Scopes, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: API Key is missing scope " + scope})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSession blocks API keys on account routes (password, sessions,
+// API key management...), these need a real login
+func RequireSession(c *gin.Context) {
+	if _, usingKey := c.Get("api_key"); usingKey {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: API Key can't be used for this route"})
+		return
+	}
+
+	c.Next()
+}
@@ -1,74 +1,88 @@
 package middleware
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rizqy/cafetify/auth"
 	"github.com/rizqy/cafetify/initializers"
 	"github.com/rizqy/cafetify/models"
 )
 
 func RequireAuth(c *gin.Context) {
-	// get token from cookie
-	tokenString, _ := c.Cookie("Authorization")
-
-	// if cookie is empty, check Authorization header
-	if tokenString == "" {
-		authHeader := c.GetHeader("Authorization")
-
-		// format for header: "Bearer <token>"
-		// get the token part only
-		if authHeader != "" {
-			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-			tokenString = strings.TrimSpace(tokenString)
-		}
+	// scripts send a personal API key instead of a JWT
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		requireAPIKey(c, apiKey)
+		return
 	}
 
+	// get token from cookie or Authorization header
+	tokenString, source := auth.TokenFromRequest(c)
+
 	// if token is still empty, return unauthorized
 	if tokenString == "" {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Token not found"})
 		return
 	}
 
-	// 2. Validate Token
-	token, _ := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// make sure that the token method conform to "SigningMethodHMAC"
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// return the secret signing key
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	// check if token is valid
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// check token expiration
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
+	// 2. Validate Token (signature + expiration)
+	claims, err := auth.ParseAccessToken(tokenString)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session Expired, Please Login Again"})
 			return
 		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Token"})
+		return
+	}
+
+	// token was revoked on logout
+	jti, _ := claims["jti"].(string)
+	if auth.IsRevoked(jti) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked, Please Login Again"})
+		return
+	}
 
-		// find user with token sub (user ID)
-		var user models.User
-		initializers.DB.First(&user, claims["sub"])
+	// find user with token sub (user ID)
+	var user models.User
+	initializers.DB.First(&user, claims["sub"])
 
-		if user.ID == 0 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			return
-		}
+	if user.ID == 0 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
 
-		// save user to context
-		c.Set("user", user)
+	// user logged out from all sessions (or changed password) after this token was made
+	issuedAt, _ := claims.GetIssuedAt()
+	if issuedAt == nil || auth.IssuedBeforeRevocation(user, issuedAt.Time) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked, Please Login Again"})
+		return
+	}
 
-		// continue to next handler
-		c.Next()
+	// the login session must still be active (not revoked from another device)
+	var session models.Session
+	initializers.DB.First(&session, auth.ClaimUint(claims, "sid"))
 
-	} else {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Token"})
+	if session.ID == 0 || session.UserID != user.ID || session.RevokedAt != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session Expired, Please Login Again"})
+		return
+	}
+
+	// update last seen, but not on every request (max once per minute)
+	if now := time.Now(); now.Sub(session.LastSeenAt) > time.Minute {
+		initializers.DB.Model(&session).Update("last_seen_at", now)
 	}
+
+	// save user to context
+	c.Set("claims", claims)
+	c.Set("session_id", session.ID)
+	c.Set("session", session)
+	c.Set("auth_source", source)
+	c.Set("user", user)
+
+	// continue to next handler
+	c.Next()
 }
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/auth"
+	"github.com/rizqy/cafetify/models"
+)
+
+// RequirePermission only lets users through whose role has the permission.
+// must be used after RequireAuth, e.g.
+//
+//	protected.DELETE("/tags/:id", middleware.RequirePermission(auth.PermModerateContent), controllers.DeleteTag)
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userContext, _ := c.Get("user")
+		user, ok := userContext.(models.User)
+
+		if !ok || !auth.HasPermission(user.Role, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: missing permission " + perm})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rizqy/cafetify/models"
+)
+
+// RequireVerifiedEmail blocks users that haven't verified their email yet.
+// must be used after RequireAuth.
+//
+// The policy is set with EMAIL_VERIFICATION_POLICY in .env:
+//
+//	"enforce" -> unverified users get 403
+//	anything else (default "off") -> everyone is allowed
+func RequireVerifiedEmail(c *gin.Context) {
+	if os.Getenv("EMAIL_VERIFICATION_POLICY") != "enforce" {
+		c.Next()
+		return
+	}
+
+	userContext, _ := c.Get("user")
+	user, ok := userContext.(models.User)
+	if !ok || user.EmailVerifiedAt == nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Please verify your email first"})
+		return
+	}
+
+	c.Next()
+}
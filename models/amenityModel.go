@@ -0,0 +1,25 @@
+package models
+
+// ==========================================
+// CAFE AMENITIES
+// ==========================================
+// Workspace attributes of a cafe, saved as columns of the cafes table.
+// nil = nobody filled it in yet (unknown is not the same as "no").
+type CafeAmenities struct {
+	WifiAvailable     *bool    `json:"wifi_available"`
+	WifiSpeedMbps     *float64 `json:"wifi_speed_mbps"`                       // measured download speed
+	PowerOutlets      string   `gorm:"type:varchar(10)" json:"power_outlets"` // "", none, few, plenty
+	SeatingCapacity   *int     `json:"seating_capacity"`
+	NoiseLevel        string   `gorm:"type:varchar(10)" json:"noise_level"` // "", quiet, moderate, loud
+	PetFriendly       *bool    `json:"pet_friendly"`
+	MotorcycleParking *bool    `json:"motorcycle_parking"`
+	CarParking        *bool    `json:"car_parking"`
+	PrayerRoom        *bool    `json:"prayer_room"` // mushola
+	SmokingArea       *bool    `json:"smoking_area"`
+}
+
+// allowed values of the text attributes
+var (
+	PowerOutletLevels = []string{"none", "few", "plenty"}
+	NoiseLevels       = []string{"quiet", "moderate", "loud"}
+)
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+// PERSONAL API KEY
+// ==========================================
+// For scripts and integrations, sent in the X-API-Key header.
+type APIKey struct {
+	gorm.Model
+
+	UserID uint   `gorm:"index;not null" json:"user_id"`
+	Name   string `gorm:"type:varchar(100);not null" json:"name"`
+
+	// first characters of the key ("cfy_Ab12Cd"), so the user can recognize it
+	Prefix string `gorm:"type:varchar(20)" json:"prefix"`
+
+	// only the sha256 of the key is stored
+	KeyHash string `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+
+	// comma separated, e.g. "read,write:cafes"
+	Scopes string `gorm:"type:varchar(255);not null" json:"scopes"`
+
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"` // nil = never expires
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
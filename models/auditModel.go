@@ -0,0 +1,25 @@
+package models
+
+import "gorm.io/gorm"
+
+// login events for the audit log
+const (
+	AuditLoginSuccess = "login_success"
+	AuditLoginFailed  = "login_failed"
+	AuditMFAFailed    = "mfa_failed"
+	AuditLockout      = "lockout"
+)
+
+// ==========================================
+// LOGIN AUDIT LOG
+// ==========================================
+type LoginAudit struct {
+	gorm.Model
+
+	UserID    *uint  `gorm:"index" json:"user_id"` // nil if the email doesn't exist
+	Email     string `gorm:"type:varchar(255);index" json:"email"`
+	Event     string `gorm:"type:varchar(30);index;not null" json:"event"`
+	IPAddress string `gorm:"type:varchar(64);index" json:"ip_address"`
+	UserAgent string `gorm:"type:varchar(255)" json:"user_agent"`
+	Details   string `gorm:"type:varchar(255)" json:"details"`
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"time"
+
+	"github.com/rizqy/cafetify/geo"
 	"gorm.io/gorm"
 )
 
@@ -10,8 +13,29 @@ import (
 type Cafe struct {
 	gorm.Model // ID, CreatedAt, UpdatedAt, DeletedAt
 
-	Name      string  `gorm:"type:varchar(255);not null" json:"name"`
-	Address   string  `gorm:"type:text" json:"address"`
+	Name      string   `gorm:"type:varchar(255);not null" json:"name"`
+	Address   string   `gorm:"type:text" json:"address"`
+	Latitude  *float64 `gorm:"index:idx_cafes_location" json:"latitude"` // nil = location unknown
+	Longitude *float64 `gorm:"index:idx_cafes_location" json:"longitude"`
+
+	// filled by the geocoder from Address (see jobs/geocode.go)
+	Street     string `gorm:"type:varchar(255)" json:"street"`
+	District   string `gorm:"type:varchar(100)" json:"district"`
+	City       string `gorm:"type:varchar(100);index" json:"city"`
+	PostalCode string `gorm:"type:varchar(20)" json:"postal_code"`
+
+	// "manual" = coordinates typed by the user, the geocoder won't overwrite them
+	LocationSource  string     `gorm:"type:varchar(20)" json:"location_source"`
+	GeocodeStatus   string     `gorm:"type:varchar(20);index" json:"geocode_status"`
+	GeocodeAttempts int        `json:"-"`
+	GeocodeNextAt   *time.Time `json:"-"`
+	GeocodeError    string     `gorm:"type:varchar(255)" json:"-"`
+
+	// IANA name like "Asia/Jakarta", empty = DEFAULT_TIMEZONE
+	Timezone string `gorm:"type:varchar(64)" json:"timezone"`
+
+	// wifi, power outlets, noise... (see amenityModel.go)
+	Amenities CafeAmenities `gorm:"embedded" json:"amenities"`
 
 	// Foreign Key
 	UserID uint `json:"user_id"`
@@ -25,8 +49,35 @@ type Cafe struct {
 
 	// Many-to-Many: Cafe can have many Tags
 	Tags []Tag `gorm:"many2many:cafe_tags;" json:"tags"`
+
+	// One-to-Many: weekly schedule + special dates (see openingHoursModel.go)
+	OpeningHours      []OpeningHour      `gorm:"foreignKey:CafeID" json:"opening_hours"`
+	OpeningExceptions []OpeningException `gorm:"foreignKey:CafeID" json:"opening_exceptions"`
+
+	// One-to-Many: photos of the cafe (incl. the ones uploaded with a rating)
+	Photos []Photo `gorm:"foreignKey:CafeID" json:"photos"`
+
+	// One-to-One: averages, medians, histograms of the ratings (see ratingStatsModel.go)
+	Stats *CafeRatingStats `gorm:"foreignKey:CafeID" json:"stats"`
+
+	// One-to-Many: menu of the cafe, only loaded by the menu endpoints
+	MenuItems []MenuItem `gorm:"foreignKey:CafeID" json:"menu_items,omitempty"`
 }
 
+// values of Cafe.LocationSource
+const (
+	LocationManual   = "manual"
+	LocationGeocoder = "geocoder"
+)
+
+// values of Cafe.GeocodeStatus ("" = never queued, e.g. no geocoder configured)
+const (
+	GeocodePending  = "pending"
+	GeocodeDone     = "done"
+	GeocodeNotFound = "not_found"
+	GeocodeFailed   = "failed" // gave up after too many errors
+)
+
 // ==========================================
 // 2. TABEL PERSONAL RATING
 // ==========================================
@@ -43,6 +94,8 @@ type PersonalRating struct {
 	PriceLevel     string `gorm:"type:varchar(50)" json:"price_level"`
 	MenuVariety    string `gorm:"type:varchar(50)" json:"menu_variety"`
 	Notes          string `gorm:"type:text" json:"notes"`
+
+	Photos []Photo `gorm:"foreignKey:RatingID" json:"photos"`
 }
 
 // ==========================================
@@ -53,6 +106,14 @@ type Tag struct {
 	Name string `gorm:"type:varchar(100);unique;not null" json:"name"`
 }
 
+// Location returns the coordinates of the cafe, ok is false when they are not set
+func (c Cafe) Location() (geo.Point, bool) {
+	if c.Latitude == nil || c.Longitude == nil {
+		return geo.Point{}, false
+	}
+	return geo.Point{Lat: *c.Latitude, Lng: *c.Longitude}, true
+}
+
 // Override table name for cafe
 func (Cafe) TableName() string {
 	return "cafes"
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+// LINKED IDENTITY (social login)
+// ==========================================
+// One row per OIDC account that can login as this user.
+type LinkedIdentity struct {
+	gorm.Model
+
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	// provider name + "sub" claim identify the account at the provider
+	Provider string `gorm:"type:varchar(50);uniqueIndex:idx_provider_subject;not null" json:"provider"`
+	Subject  string `gorm:"type:varchar(255);uniqueIndex:idx_provider_subject;not null" json:"-"`
+
+	Email       string    `gorm:"type:varchar(255)" json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// ==========================================
+// OIDC LOGIN STATE
+// ==========================================
+// Saved between the redirect to the provider and the callback.
+type OIDCLoginState struct {
+	gorm.Model
+
+	StateHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Provider     string     `gorm:"type:varchar(50);not null" json:"provider"`
+	Nonce        string     `gorm:"type:varchar(100);not null" json:"-"`
+	CodeVerifier string     `gorm:"type:varchar(100);not null" json:"-"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+}
+
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
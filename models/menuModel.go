@@ -0,0 +1,38 @@
+package models
+
+import "gorm.io/gorm"
+
+// ==========================================
+// MENU ITEM
+// ==========================================
+// What a cafe sells. Price is in the smallest unit of Currency
+// (rupiah has no cents, so 28000 = Rp 28.000).
+type MenuItem struct {
+	gorm.Model
+
+	CafeID uint `gorm:"index;not null" json:"cafe_id"`
+	UserID uint `json:"user_id"` // who added it
+
+	Name     string `gorm:"type:varchar(150);not null" json:"name"`
+	Category string `gorm:"type:varchar(50);index" json:"category"` // e.g. coffee, non-coffee, food, snack, dessert
+	Price    int64  `gorm:"index" json:"price"`
+	Currency string `gorm:"type:varchar(3);default:IDR" json:"currency"`
+
+	// dietary flags
+	Vegetarian bool `json:"vegetarian"`
+	Vegan      bool `json:"vegan"`
+	GlutenFree bool `json:"gluten_free"`
+	DairyFree  bool `json:"dairy_free"`
+	Halal      bool `json:"halal"`
+
+	Available bool `json:"available"` // false = sold out / seasonal
+}
+
+// DietaryColumns maps the dietary filter names of the API to the columns
+var DietaryColumns = map[string]string{
+	"vegetarian":  "vegetarian",
+	"vegan":       "vegan",
+	"gluten_free": "gluten_free",
+	"dairy_free":  "dairy_free",
+	"halal":       "halal",
+}
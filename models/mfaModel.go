@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+// 2FA RECOVERY CODE
+// ==========================================
+// Used when the phone with the authenticator app is lost.
+// Every code works only once.
+type RecoveryCode struct {
+	gorm.Model
+
+	UserID   uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "gorm.io/gorm"
+
+// ==========================================
+// MODERATION LOG
+// ==========================================
+// One row for every change a moderator / admin makes on data of another user.
+type ModerationLog struct {
+	gorm.Model
+
+	ActorID uint `gorm:"index;not null" json:"actor_id"`
+	Actor   User `gorm:"foreignKey:ActorID" json:"actor"`
+
+	Action     string `gorm:"type:varchar(50);not null" json:"action"`            // e.g. "cafe.update", "tag.delete"
+	TargetType string `gorm:"type:varchar(50);index;not null" json:"target_type"` // cafe, rating, tag, user
+	TargetID   uint   `gorm:"index" json:"target_id"`
+	OwnerID    uint   `json:"owner_id"` // user who owned the data (0 for shared data like tags)
+
+	// data before / after the change as JSON
+	Details string `gorm:"type:text" json:"details"`
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+	_ "time/tzdata" // timezones also work on servers without zoneinfo files
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+// OPENING HOURS
+// ==========================================
+// Weekly schedule of a cafe. Times are "HH:MM" in the timezone of the cafe.
+// When Closes is not after Opens the range goes over midnight,
+// e.g. Friday 18:00 - 02:00 is open until Saturday 02:00.
+// A day can have several rows (e.g. 07:00 - 11:00 and 17:00 - 23:00).
+type OpeningHour struct {
+	gorm.Model
+
+	CafeID  uint         `gorm:"index;not null" json:"cafe_id"`
+	Weekday time.Weekday `gorm:"not null" json:"weekday"` // 0 = Sunday ... 6 = Saturday
+	Opens   string       `gorm:"type:varchar(5);not null" json:"opens"`
+	Closes  string       `gorm:"type:varchar(5);not null" json:"closes"`
+}
+
+// ==========================================
+// OPENING EXCEPTION
+// ==========================================
+// Replaces the weekly schedule on one date (holidays, Lebaran, events).
+// Closed = the whole day is closed, otherwise Opens / Closes are the hours of that date.
+type OpeningException struct {
+	gorm.Model
+
+	CafeID uint   `gorm:"index;not null" json:"cafe_id"`
+	Date   string `gorm:"type:varchar(10);index;not null" json:"date"` // YYYY-MM-DD in the cafe timezone
+	Closed bool   `json:"closed"`
+	Opens  string `gorm:"type:varchar(5)" json:"opens"`
+	Closes string `gorm:"type:varchar(5)" json:"closes"`
+	Note   string `gorm:"type:varchar(255)" json:"note"`
+}
+
+const DateLayout = "2006-01-02"
+
+// DefaultTimezone is used for cafes without a timezone (DEFAULT_TIMEZONE, default Asia/Jakarta)
+func DefaultTimezone() string {
+	if tz := os.Getenv("DEFAULT_TIMEZONE"); tz != "" {
+		return tz
+	}
+	return "Asia/Jakarta"
+}
+
+var locationCache sync.Map // name -> *time.Location
+
+// LoadTimezone is time.LoadLocation with a cache, it is called for every cafe in a list
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone()
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// ParseClock reads "HH:MM" as minutes after midnight. "24:00" is allowed as closing time
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, errors.New("time must be HH:MM")
+}
+
+// TimeLocation returns the timezone of the cafe
+func (c Cafe) TimeLocation() *time.Location {
+	loc, err := LoadTimezone(c.Timezone)
+	if err != nil {
+		loc, _ = LoadTimezone(DefaultTimezone())
+	}
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsOpenAt checks the schedule at t. OpeningHours and OpeningExceptions
+// must be loaded (exceptions at least for the day of t and the day before)
+func (c Cafe) IsOpenAt(t time.Time) bool {
+	local := t.In(c.TimeLocation())
+	minute := local.Hour()*60 + local.Minute()
+
+	// a range of today that started already
+	for _, r := range c.rangesOn(local) {
+		if minute >= r.start && minute < r.end {
+			return true
+		}
+	}
+
+	// a range of yesterday that goes over midnight
+	for _, r := range c.rangesOn(local.AddDate(0, 0, -1)) {
+		if minute+24*60 >= r.start && minute+24*60 < r.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minutes after midnight of the day it starts, end can be > 24h (over midnight)
+type openRange struct{ start, end int }
+
+func (c Cafe) rangesOn(day time.Time) []openRange {
+	date := day.Format(DateLayout)
+
+	var exceptions []OpeningException
+	for _, e := range c.OpeningExceptions {
+		if e.Date == date {
+			exceptions = append(exceptions, e)
+		}
+	}
+
+	var ranges []openRange
+	if len(exceptions) > 0 {
+		for _, e := range exceptions {
+			if e.Closed {
+				return nil
+			}
+			if r, ok := newOpenRange(e.Opens, e.Closes); ok {
+				ranges = append(ranges, r)
+			}
+		}
+		return ranges
+	}
+
+	for _, h := range c.OpeningHours {
+		if h.Weekday != day.Weekday() {
+			continue
+		}
+		if r, ok := newOpenRange(h.Opens, h.Closes); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+func newOpenRange(opens, closes string) (openRange, bool) {
+	start, err1 := ParseClock(opens)
+	end, err2 := ParseClock(closes)
+	if err1 != nil || err2 != nil {
+		return openRange{}, false
+	}
+	// over midnight (or 00:00 - 00:00 = open the whole day)
+	if end <= start {
+		end += 24 * 60
+	}
+	return openRange{start: start, end: end}, true
+}
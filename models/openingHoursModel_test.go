@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// week of 2026-10-12 (Monday) ... 2026-10-18 (Sunday)
+func testSchedule(timezone string, exceptions ...OpeningException) Cafe {
+	return Cafe{
+		Timezone: timezone,
+		OpeningHours: []OpeningHour{
+			{Weekday: time.Friday, Opens: "18:00", Closes: "02:00"},
+			{Weekday: time.Saturday, Opens: "08:00", Closes: "24:00"},
+			{Weekday: time.Sunday, Opens: "00:00", Closes: "00:00"},
+			{Weekday: time.Monday, Opens: "07:00", Closes: "11:00"},
+			{Weekday: time.Monday, Opens: "17:00", Closes: "23:00"},
+		},
+		OpeningExceptions: exceptions,
+	}
+}
+
+func TestIsOpenAt(t *testing.T) {
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, jakarta)
+	}
+
+	weekly := testSchedule("")
+	withExceptions := testSchedule("",
+		OpeningException{Date: "2026-10-12", Closed: true},                    // Monday
+		OpeningException{Date: "2026-10-14", Opens: "10:00", Closes: "12:00"}, // Wednesday, normally closed
+		OpeningException{Date: "2026-10-16", Opens: "09:00", Closes: "12:00"}, // Friday, no night opening
+	)
+	makassar := testSchedule("Asia/Makassar") // UTC+8, one hour ahead of Jakarta
+
+	tests := []struct {
+		name string
+		cafe Cafe
+		t    time.Time
+		want bool
+	}{
+		{"before opening", weekly, at(16, 17, 59), false},
+		{"at opening", weekly, at(16, 18, 0), true},
+		{"before midnight", weekly, at(16, 23, 59), true},
+		{"overnight after midnight", weekly, at(17, 1, 59), true},
+		{"overnight at closing", weekly, at(17, 2, 0), false},
+		{"closing 24:00", weekly, at(17, 23, 59), true},
+		{"00:00 - 00:00 is the whole day", weekly, at(18, 0, 0), true},
+		{"whole day ends at midnight", weekly, at(19, 0, 30), false},
+		{"second range of the day", weekly, at(19, 17, 30), true},
+		{"between two ranges", weekly, at(19, 12, 0), false},
+		{"day without hours", weekly, at(15, 10, 0), false},
+		{"other offset of the same instant", weekly, time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC), true},
+
+		{"exception closes the day", withExceptions, at(12, 8, 0), false},
+		{"exception opens a closed day", withExceptions, at(14, 11, 0), true},
+		{"exception end is exclusive", withExceptions, at(14, 12, 0), false},
+		{"exception replaces the weekday hours", withExceptions, at(16, 19, 0), false},
+		{"exception hours", withExceptions, at(16, 9, 30), true},
+		{"exception also removes the overnight range", withExceptions, at(17, 1, 0), false},
+
+		// 10:30 UTC = 17:30 Jakarta = 18:30 Makassar
+		{"cafe timezone is used", makassar, time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC), true},
+		{"default timezone is used", weekly, time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cafe.IsOpenAt(tt.t); got != tt.want {
+				t.Errorf("IsOpenAt(%s) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"00:00", 0, false},
+		{"07:30", 450, false},
+		{"23:59", 1439, false},
+		{"24:00", 1440, false},
+		{"24:01", 0, true},
+		{"7:30", 450, false},
+		{"07.30", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseClock(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseClock(%q) = %d, %v, want %d, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import "gorm.io/gorm"
+
+// ==========================================
+// PHOTO
+// ==========================================
+// A photo of a cafe, optionally uploaded together with a rating (RatingID).
+// Rating photos are also cafe photos, so CafeID is always set.
+// The URLs are saved at upload time from the storage that was used.
+type Photo struct {
+	gorm.Model
+
+	UserID   uint  `gorm:"index;not null" json:"user_id"`
+	CafeID   uint  `gorm:"index;not null" json:"cafe_id"`
+	RatingID *uint `gorm:"index" json:"rating_id"`
+
+	// storage keys, needed to delete the files
+	Key      string `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbKey string `gorm:"type:varchar(255);not null" json:"-"`
+
+	URL          string `gorm:"type:varchar(512);not null" json:"url"`
+	ThumbnailURL string `gorm:"type:varchar(512);not null" json:"thumbnail_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Size         int64  `json:"size"` // bytes of the stored (re-encoded) photo
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ==========================================
+// TABEL CAFE RATING STATS
+// ==========================================
+// aggregates of the personal ratings of one cafe, so a list doesn't need
+// every rating. rebuilt by RefreshRatingStats after a rating changes
+type CafeRatingStats struct {
+	CafeID uint `gorm:"primaryKey;autoIncrement:false" json:"-"`
+
+	RatingCount    int     `gorm:"index" json:"rating_count"`
+	AmbienceMean   float64 `gorm:"index" json:"ambience_mean"`
+	AmbienceMedian float64 `json:"ambience_median"`
+	ServiceMean    float64 `gorm:"index" json:"service_mean"`
+	ServiceMedian  float64 `json:"service_median"`
+
+	AmbienceHistogram Histogram `gorm:"type:varchar(255)" json:"ambience_histogram"`
+	ServiceHistogram  Histogram `gorm:"type:varchar(255)" json:"service_histogram"`
+
+	// most common answer, "" without ratings
+	TopPriceLevel  string `gorm:"type:varchar(50)" json:"top_price_level"`
+	TopMenuVariety string `gorm:"type:varchar(50)" json:"top_menu_variety"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Histogram counts the ratings per star, index 0 = 1 star ... index 4 = 5 stars.
+// stored as a json array
+type Histogram [5]int
+
+func (h Histogram) Value() (driver.Value, error) {
+	data, err := json.Marshal(h)
+	return string(data), err
+}
+
+func (h *Histogram) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*h = Histogram{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	}
+	return errors.New("histogram: unsupported type")
+}
+
+func (h *Histogram) add(stars int) {
+	// old rows were saved without a range check, count them in the nearest bucket
+	h[min(max(stars, 1), 5)-1]++
+}
+
+// RefreshRatingStats recomputes the stats of one cafe from its ratings.
+// call it after the transaction that changed the ratings is committed:
+// the cafe row is locked first, so two refreshes of the same cafe run one after
+// the other and the last one always sees every committed rating.
+// a deleted cafe loses its stats row
+func RefreshRatingStats(db *gorm.DB, cafeID uint) (CafeRatingStats, error) {
+	stats := CafeRatingStats{CafeID: cafeID}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var cafe Cafe
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&cafe, cafeID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Delete(&CafeRatingStats{}, cafeID).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		var ratings []PersonalRating
+		if err := tx.Select("ambience_rating", "service_rating", "price_level", "menu_variety").
+			Where("cafe_id = ?", cafeID).
+			Find(&ratings).Error; err != nil {
+			return err
+		}
+
+		stats = computeRatingStats(cafeID, ratings)
+
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&stats).Error
+	})
+
+	return stats, err
+}
+
+func computeRatingStats(cafeID uint, ratings []PersonalRating) CafeRatingStats {
+	stats := CafeRatingStats{CafeID: cafeID, RatingCount: len(ratings)}
+	if len(ratings) == 0 {
+		return stats
+	}
+
+	ambience := make([]int, len(ratings))
+	service := make([]int, len(ratings))
+	priceLevels := map[string]int{}
+	menuVarieties := map[string]int{}
+
+	for i, r := range ratings {
+		ambience[i] = r.AmbienceRating
+		service[i] = r.ServiceRating
+		stats.AmbienceHistogram.add(r.AmbienceRating)
+		stats.ServiceHistogram.add(r.ServiceRating)
+		if r.PriceLevel != "" {
+			priceLevels[r.PriceLevel]++
+		}
+		if r.MenuVariety != "" {
+			menuVarieties[r.MenuVariety]++
+		}
+	}
+
+	stats.AmbienceMean, stats.AmbienceMedian = meanMedian(ambience)
+	stats.ServiceMean, stats.ServiceMedian = meanMedian(service)
+	stats.TopPriceLevel = mostCommon(priceLevels)
+	stats.TopMenuVariety = mostCommon(menuVarieties)
+	return stats
+}
+
+func meanMedian(values []int) (float64, float64) {
+	slices.Sort(values)
+
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	mean := float64(sum) / float64(len(values))
+
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return mean, float64(values[mid])
+	}
+	return mean, float64(values[mid-1]+values[mid]) / 2
+}
+
+// the value with the highest count, a tie goes to the alphabetically first one
+// so the result doesn't change between refreshes
+func mostCommon(counts map[string]int) string {
+	best, bestCount := "", 0
+	for value, count := range counts {
+		if count > bestCount || (count == bestCount && value < best) {
+			best, bestCount = value, count
+		}
+	}
+	return best
+}
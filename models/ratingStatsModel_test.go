@@ -0,0 +1,90 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMeanMedian(t *testing.T) {
+	tests := []struct {
+		values    []int
+		mean, med float64
+	}{
+		{[]int{4}, 4, 4},
+		{[]int{5, 1, 3}, 3, 3},
+		{[]int{5, 4, 2, 1}, 3, 3}, // even count: average of the two middle values
+		{[]int{4, 5}, 4.5, 4.5},
+		{[]int{5, 5, 5, 1}, 4, 5},
+	}
+
+	for _, tt := range tests {
+		mean, med := meanMedian(append([]int(nil), tt.values...))
+		if mean != tt.mean || med != tt.med {
+			t.Errorf("meanMedian(%v) = %v, %v, want %v, %v", tt.values, mean, med, tt.mean, tt.med)
+		}
+	}
+}
+
+func TestComputeRatingStats(t *testing.T) {
+	ratings := []PersonalRating{
+		{AmbienceRating: 5, ServiceRating: 4, PriceLevel: "murah", MenuVariety: "banyak"},
+		{AmbienceRating: 3, ServiceRating: 4, PriceLevel: "sedang", MenuVariety: "banyak"},
+		{AmbienceRating: 4, ServiceRating: 2, PriceLevel: "murah"},
+		{AmbienceRating: 2, ServiceRating: 5, PriceLevel: "sedang", MenuVariety: "sedikit"},
+	}
+
+	want := CafeRatingStats{
+		CafeID:            7,
+		RatingCount:       4,
+		AmbienceMean:      3.5,
+		AmbienceMedian:    3.5,
+		ServiceMean:       3.75,
+		ServiceMedian:     4,
+		AmbienceHistogram: Histogram{0, 1, 1, 1, 1},
+		ServiceHistogram:  Histogram{0, 1, 0, 2, 1},
+		TopPriceLevel:     "murah", // tie with "sedang", alphabetically first
+		TopMenuVariety:    "banyak",
+	}
+
+	if got := computeRatingStats(7, ratings); !reflect.DeepEqual(got, want) {
+		t.Errorf("computeRatingStats =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestComputeRatingStatsEdgeCases(t *testing.T) {
+	if got := computeRatingStats(1, nil); got != (CafeRatingStats{CafeID: 1}) {
+		t.Errorf("no ratings = %+v, want only the cafe id", got)
+	}
+
+	// old rows without range check land in the nearest bucket, the mean keeps the real value
+	got := computeRatingStats(1, []PersonalRating{{AmbienceRating: 0, ServiceRating: 9}})
+	if got.AmbienceHistogram != (Histogram{1, 0, 0, 0, 0}) || got.ServiceHistogram != (Histogram{0, 0, 0, 0, 1}) {
+		t.Errorf("histograms = %v, %v", got.AmbienceHistogram, got.ServiceHistogram)
+	}
+	if got.ServiceMean != 9 || got.TopPriceLevel != "" {
+		t.Errorf("stats = %+v", got)
+	}
+}
+
+func TestHistogramValueScan(t *testing.T) {
+	h := Histogram{1, 0, 3, 12, 40}
+	v, err := h.Value()
+	if err != nil || v != "[1,0,3,12,40]" {
+		t.Fatalf("Value() = %v, %v", v, err)
+	}
+
+	for _, src := range []interface{}{v, []byte(v.(string))} {
+		var got Histogram
+		if err := got.Scan(src); err != nil || got != h {
+			t.Errorf("Scan(%T) = %v, %v", src, got, err)
+		}
+	}
+
+	var got Histogram
+	if err := got.Scan(nil); err != nil || got != (Histogram{}) {
+		t.Errorf("Scan(nil) = %v, %v", got, err)
+	}
+	if err := got.Scan(42); err == nil {
+		t.Error("Scan(int) should fail")
+	}
+}
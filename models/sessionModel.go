@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+// SESSION (one row per login / device)
+// ==========================================
+type Session struct {
+	gorm.Model
+
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	// refresh token family of this login (see RefreshToken)
+	FamilyID string `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+
+	// sha256 of the CSRF token (synchronizer token for cookie logins)
+	CSRFTokenHash string `gorm:"column:csrf_token_hash;type:varchar(64)" json:"-"`
+
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress  string     `gorm:"type:varchar(64)" json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
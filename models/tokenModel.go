@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+// REFRESH TOKEN
+// ==========================================
+// Every login starts a new "family". Each /refresh rotates the token:
+// the old one gets RotatedAt and a new one is created in the same family.
+// If a rotated token is used again, the whole family is revoked.
+type RefreshToken struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	FamilyID string `gorm:"type:varchar(64);index;not null" json:"family_id"`
+
+	// only the sha256 of the token is stored, never the token itself
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// ==========================================
+// REVOKED ACCESS TOKEN
+// ==========================================
+// Access tokens are JWT so they can't be deleted, instead the jti is saved
+// here on logout and RequireAuth rejects it until it expires.
+type RevokedToken struct {
+	gorm.Model
+
+	JTI       string    `gorm:"column:jti;type:varchar(64);uniqueIndex;not null" json:"jti"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
+
+// ==========================================
+// PASSWORD RESET TOKEN
+// ==========================================
+// Single use: UsedAt is set when the password is changed with it.
+type PasswordResetToken struct {
+	gorm.Model
+
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// ==========================================
+// EMAIL VERIFICATION TOKEN
+// ==========================================
+// Email is saved too, the token only verifies the address it was sent to.
+type EmailVerificationToken struct {
+	gorm.Model
+
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Email     string     `gorm:"type:varchar(255);not null" json:"email"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// ==========================================
+// EMAIL CHANGE REQUEST
+// ==========================================
+// The new email is only saved on the user after the link sent to it is
+// confirmed. The old email gets a notice with an undo link.
+type EmailChangeRequest struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	OldEmail string `gorm:"type:varchar(255);not null" json:"old_email"`
+	NewEmail string `gorm:"type:varchar(255);not null" json:"new_email"`
+
+	ConfirmTokenHash string `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	UndoTokenHash    string `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+
+	ExpiresAt     time.Time  `json:"expires_at"`      // confirm link
+	UndoExpiresAt time.Time  `json:"undo_expires_at"` // undo link
+	ConfirmedAt   *time.Time `json:"confirmed_at"`
+	CanceledAt    *time.Time `json:"canceled_at"`
+}
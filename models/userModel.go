@@ -1,6 +1,17 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// roles, from lowest to highest
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
 
 type User struct {
 	gorm.Model // Automatically create ID, CreatedAt, UpdatedAt, DeletedAt
@@ -13,4 +24,45 @@ type User struct {
 	Password string `json:"-"`
 
 	Username string `json:"username"`
+
+	// user / moderator / admin (see auth.HasPermission)
+	// the fields below are hidden because the user is preloaded as owner of cafes,
+	// they are only sent to the user themselves through Account()
+	Role string `gorm:"type:varchar(20);not null;default:user" json:"-"`
+
+	// --- Two factor authentication (TOTP) ---
+	// secret is saved on enroll, 2FA is only active after confirm (TOTPEnabledAt)
+	TOTPSecret    string     `gorm:"column:totp_secret;type:varchar(64)" json:"-"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"-"`
+	TOTPLastStep  int64      `gorm:"column:totp_last_step" json:"-"` // last used time step, blocks replay
+
+	// nil until the user clicks the link in the verification email
+	EmailVerifiedAt *time.Time `json:"-"`
+
+	// account deletion: the data is removed by the purge job after DeletionScheduledAt,
+	// until then the user can login and cancel
+	DeletionScheduledAt *time.Time `json:"-"`
+
+	// every token issued before this time is rejected ("log out all sessions")
+	TokensRevokedAt *time.Time `json:"-"`
+}
+
+// Account is the user as shown to themselves (profile, export), with the
+// account state that is hidden from the public User JSON
+type Account struct {
+	User
+	Role                string     `json:"role"`
+	TOTPEnabledAt       *time.Time `json:"totp_enabled_at"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+}
+
+func (u User) Account() Account {
+	return Account{
+		User:                u,
+		Role:                u.Role,
+		TOTPEnabledAt:       u.TOTPEnabledAt,
+		EmailVerifiedAt:     u.EmailVerifiedAt,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+	}
 }
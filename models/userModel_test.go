@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserJSONHidesAccountState(t *testing.T) {
+	now := time.Now()
+	u := User{Email: "budi@example.com", Username: "budi", Role: RoleAdmin, TOTPSecret: "SECRET",
+		TOTPEnabledAt: &now, EmailVerifiedAt: &now, DeletionScheduledAt: &now}
+
+	public, _ := json.Marshal(u)
+	for _, key := range []string{"role", "totp_enabled_at", "email_verified_at", "deletion_scheduled_at", "SECRET"} {
+		if strings.Contains(string(public), key) {
+			t.Errorf("public user JSON contains %q: %s", key, public)
+		}
+	}
+
+	own, _ := json.Marshal(u.Account())
+	var got map[string]interface{}
+	json.Unmarshal(own, &got)
+	for _, key := range []string{"username", "email", "role", "totp_enabled_at", "email_verified_at", "deletion_scheduled_at"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("account JSON misses %q: %s", key, own)
+		}
+	}
+	if got["role"] != RoleAdmin || strings.Contains(string(own), "SECRET") {
+		t.Errorf("account JSON = %s", own)
+	}
+}
@@ -0,0 +1,48 @@
+package oidc
+
+import (
+	"os"
+	"strings"
+)
+
+// ProvidersFromEnv reads the providers from .env
+//
+//	OIDC_PROVIDERS=google,mock
+//	OIDC_GOOGLE_ISSUER=https://accounts.google.com
+//	OIDC_GOOGLE_CLIENT_ID=...
+//	OIDC_GOOGLE_CLIENT_SECRET=...          (optional with PKCE)
+//	OIDC_GOOGLE_REDIRECT_URL=http://localhost:8080/auth/oidc/google/callback
+//	OIDC_GOOGLE_SCOPES=openid email profile (optional)
+//
+// a provider without issuer or client id is skipped
+func ProvidersFromEnv() map[string]*Provider {
+	providers := map[string]*Provider{}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		cfg := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			cfg.Scopes = strings.Fields(scopes)
+		}
+
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			continue
+		}
+
+		providers[name] = NewProvider(cfg)
+	}
+
+	return providers
+}
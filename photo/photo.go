@@ -0,0 +1,112 @@
+package photo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+const (
+	// longest side of the saved photo and of the thumbnail
+	maxSide   = 2048
+	thumbSide = 320
+
+	// stop decompression bombs (a small file that decodes to a huge image).
+	// 24 MP is about 96 MB as RGBA, still enough for every phone camera
+	maxPixels = 24_000_000
+
+	// decoded photos are big, only this many are processed at the same time,
+	// the other uploads wait for a free slot
+	maxConcurrent = 2
+)
+
+var slots = make(chan struct{}, maxConcurrent)
+
+var (
+	ErrTooLarge        = errors.New("file is too large")
+	ErrUnsupportedType = errors.New("only JPEG, PNG and GIF images are allowed")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// allowed types, detected from the file content (the filename / header of the client is not trusted)
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Processed is an upload that is safe to store: re-encoded as JPEG, so EXIF
+// (GPS position, camera serial...) and anything hidden in the file is gone
+type Processed struct {
+	Full   []byte
+	Thumb  []byte
+	Width  int
+	Height int
+}
+
+// Process reads at most maxBytes from r, checks the type, applies the EXIF
+// rotation and returns the resized photo + thumbnail
+func Process(r io.Reader, maxBytes int64) (Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return Processed{}, err
+	}
+	if int64(len(data)) > maxBytes {
+		return Processed{}, ErrTooLarge
+	}
+
+	if !allowedTypes[http.DetectContentType(data)] {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return Processed{}, ErrTooManyPixels
+	}
+
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	// JPEG has no transparency, flatten on white
+	img := image.NewRGBA(src.Bounds().Sub(src.Bounds().Min))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Over)
+
+	// shrink first, so the rotation below copies the small image and not the original.
+	// fit only looks at the longest side, rotating afterwards gives the same size
+	full := fit(img, maxSide)
+
+	// phones save the photo sideways + an EXIF flag, the flag is removed so rotate the pixels
+	full = orient(full, exifOrientation(data))
+	thumb := fit(full, thumbSide)
+
+	var fullBuf, thumbBuf bytes.Buffer
+	if err := jpeg.Encode(&fullBuf, full, &jpeg.Options{Quality: 85}); err != nil {
+		return Processed{}, err
+	}
+	if err := jpeg.Encode(&thumbBuf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return Processed{}, err
+	}
+
+	return Processed{
+		Full:   fullBuf.Bytes(),
+		Thumb:  thumbBuf.Bytes(),
+		Width:  full.Bounds().Dx(),
+		Height: full.Bounds().Dy(),
+	}, nil
+}
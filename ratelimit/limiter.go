@@ -0,0 +1,56 @@
+package ratelimit
+
+import "time"
+
+// Limiter counts failed attempts per key (e.g. "account:a@b.com", "ip:1.2.3.4")
+// and locks the key with exponential backoff.
+//
+// MemoryLimiter is the only backend for now. A shared store (Redis, DB...)
+// only has to implement this interface to work with several servers.
+//
+// An attempt is counted BEFORE the password is checked (Reserve), in the same
+// step as the lock check. Checking first and counting after a slow bcrypt
+// compare would let many parallel guesses pass the check before the first one
+// is counted. A successful attempt gives its reservation back (Refund / Reset).
+type Limiter interface {
+	// Check returns how long the key is still locked (0 = not locked)
+	Check(key string) (time.Duration, error)
+
+	// Reserve counts an attempt as failed unless the key is locked.
+	// returns how long the key is still locked, 0 = the attempt was counted and may go on
+	Reserve(key string) (time.Duration, error)
+
+	// Refund takes back one reserved attempt, e.g. when the password was correct
+	Refund(key string) error
+
+	// Reset forgets the failed attempts of the key, e.g. after a successful login
+	Reset(key string) error
+}
+
+// Policy decides when and how long a key gets locked
+type Policy struct {
+	FreeAttempts int           // failures allowed before the first lock
+	BaseLockout  time.Duration // first lock, doubled for every next failure
+	MaxLockout   time.Duration // upper limit of one lock
+	Window       time.Duration // failures are forgotten after this time without new failures
+}
+
+// Lockout returns the lock duration after `failures` failed attempts
+func (p Policy) Lockout(failures int) time.Duration {
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	lock := p.BaseLockout
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		lock *= 2
+		if lock >= p.MaxLockout {
+			return p.MaxLockout
+		}
+	}
+
+	if lock > p.MaxLockout {
+		return p.MaxLockout
+	}
+	return lock
+}
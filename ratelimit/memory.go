@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// expired entries are removed at most this often
+	sweepInterval = time.Minute
+
+	// hard limit of the map, someone rotating IPs or emails can't grow it further
+	defaultMaxEntries = 100_000
+)
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLimiter keeps the counters in a map. good enough for one server,
+// the counters are lost on restart
+type MemoryLimiter struct {
+	policy     Policy
+	mu         sync.Mutex
+	entries    map[string]*entry
+	now        func() time.Time
+	lastSweep  time.Time
+	maxEntries int
+}
+
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{
+		policy:     policy,
+		entries:    map[string]*entry{},
+		now:        time.Now,
+		lastSweep:  time.Now(),
+		maxEntries: defaultMaxEntries,
+	}
+}
+
+func (l *MemoryLimiter) Check(key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.get(key)
+	if e == nil {
+		return 0, nil
+	}
+
+	if wait := e.lockedUntil.Sub(l.now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+func (l *MemoryLimiter) Reserve(key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	e := l.get(key)
+	if e != nil {
+		if wait := e.lockedUntil.Sub(now); wait > 0 {
+			return wait, nil
+		}
+	} else {
+		l.makeRoom()
+		e = &entry{}
+		l.entries[key] = e
+	}
+
+	e.failures++
+	e.lastFailure = now
+
+	// the lock starts now, this attempt still goes on (it was allowed when it came in)
+	if lock := l.policy.Lockout(e.failures); lock > 0 {
+		e.lockedUntil = now.Add(lock)
+	}
+
+	return 0, nil
+}
+
+func (l *MemoryLimiter) Refund(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.get(key)
+	if e == nil {
+		return nil
+	}
+
+	e.failures--
+	if e.failures <= 0 {
+		delete(l.entries, key)
+		return nil
+	}
+	// the lock this attempt started is taken back too
+	if l.policy.Lockout(e.failures) == 0 {
+		e.lockedUntil = time.Time{}
+	}
+	return nil
+}
+
+func (l *MemoryLimiter) Reset(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+	return nil
+}
+
+// entry of the key, nil if there is none or it is older than the window
+func (l *MemoryLimiter) get(key string) *entry {
+	e, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if l.expired(e, l.now()) {
+		delete(l.entries, key)
+		return nil
+	}
+	return e
+}
+
+func (l *MemoryLimiter) expired(e *entry, now time.Time) bool {
+	return now.Sub(e.lastFailure) > l.policy.Window && now.After(e.lockedUntil)
+}
+
+// remove expired entries, once per sweepInterval
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, e := range l.entries {
+		if l.expired(e, now) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// the map is full: drop an entry that isn't locked (a running lockout is worth
+// more than a few counted failures), only when every entry is locked drop any
+func (l *MemoryLimiter) makeRoom() {
+	if len(l.entries) < l.maxEntries {
+		return
+	}
+
+	now := l.now()
+	victim := ""
+	for key, e := range l.entries {
+		victim = key
+		if !now.Before(e.lockedUntil) {
+			break
+		}
+	}
+	delete(l.entries, victim)
+}
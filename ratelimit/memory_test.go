@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseLockout:  time.Minute,
+	MaxLockout:   10 * time.Minute,
+	Window:       time.Hour,
+}
+
+// limiter with a clock the test moves by hand
+func newTestLimiter(policy Policy) (*MemoryLimiter, *time.Time) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(policy)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	return l, &now
+}
+
+func TestPolicyLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Minute},
+		{5, 2 * time.Minute},
+		{6, 4 * time.Minute},
+		{7, 8 * time.Minute},
+		{8, 10 * time.Minute}, // capped
+		{50, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy.Lockout(tt.failures); got != tt.want {
+			t.Errorf("Lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestReserveLocksAfterFreeAttempts(t *testing.T) {
+	l, now := newTestLimiter(testPolicy)
+
+	// free attempts + the one that starts the lock are let through
+	for i := 1; i <= testPolicy.FreeAttempts+1; i++ {
+		if wait, _ := l.Reserve("ip:1"); wait != 0 {
+			t.Fatalf("attempt %d locked for %v", i, wait)
+		}
+	}
+
+	wait, _ := l.Reserve("ip:1")
+	if wait != time.Minute {
+		t.Fatalf("locked for %v, want 1m", wait)
+	}
+	if wait, _ := l.Check("ip:1"); wait != time.Minute {
+		t.Errorf("Check = %v, want 1m", wait)
+	}
+	if wait, _ := l.Check("ip:2"); wait != 0 {
+		t.Errorf("other key locked for %v", wait)
+	}
+
+	// the lock runs out, the next attempt doubles it
+	*now = now.Add(time.Minute)
+	if wait, _ := l.Reserve("ip:1"); wait != 0 {
+		t.Fatalf("still locked for %v after the lock", wait)
+	}
+	if wait, _ := l.Check("ip:1"); wait != 2*time.Minute {
+		t.Errorf("second lock = %v, want 2m", wait)
+	}
+}
+
+func TestFailuresExpireAfterWindow(t *testing.T) {
+	l, now := newTestLimiter(testPolicy)
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		l.Reserve("account:a@b.c")
+	}
+
+	*now = now.Add(testPolicy.Window + time.Second)
+
+	// counting starts again, so the free attempts are back
+	for i := 1; i <= testPolicy.FreeAttempts; i++ {
+		l.Reserve("account:a@b.c")
+		if wait, _ := l.Check("account:a@b.c"); wait != 0 {
+			t.Fatalf("attempt %d after the window locked for %v", i, wait)
+		}
+	}
+}
+
+func TestRefundAndReset(t *testing.T) {
+	l, _ := newTestLimiter(testPolicy)
+
+	// a correct password after the lock started takes the lock back
+	for i := 0; i <= testPolicy.FreeAttempts; i++ {
+		l.Reserve("ip:1")
+	}
+	l.Refund("ip:1")
+	if wait, _ := l.Check("ip:1"); wait != 0 {
+		t.Errorf("locked for %v after refund", wait)
+	}
+	if got := l.entries["ip:1"].failures; got != testPolicy.FreeAttempts {
+		t.Errorf("failures = %d, want %d", got, testPolicy.FreeAttempts)
+	}
+
+	l.Reset("ip:1")
+	if _, ok := l.entries["ip:1"]; ok {
+		t.Error("entry still there after reset")
+	}
+
+	// refunding an unknown key is a no-op
+	if err := l.Refund("ip:unknown"); err != nil || len(l.entries) != 0 {
+		t.Errorf("Refund of unknown key: %v, %d entries", err, len(l.entries))
+	}
+}
+
+// many parallel guesses: only as many pass as one after the other would
+func TestReserveConcurrent(t *testing.T) {
+	l := NewMemoryLimiter(testPolicy)
+
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if wait, _ := l.Reserve("account:victim"); wait == 0 {
+				passed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := passed.Load(); got != int32(testPolicy.FreeAttempts+1) {
+		t.Errorf("%d attempts passed, want %d", got, testPolicy.FreeAttempts+1)
+	}
+}
+
+func TestSweepRemovesExpiredEntries(t *testing.T) {
+	l, now := newTestLimiter(testPolicy)
+
+	for i := 0; i < 10; i++ {
+		l.Reserve(fmt.Sprintf("ip:%d", i))
+	}
+
+	*now = now.Add(testPolicy.Window + sweepInterval)
+	l.Reserve("ip:new")
+
+	if len(l.entries) != 1 {
+		t.Errorf("%d entries after sweep, want 1", len(l.entries))
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	l, _ := newTestLimiter(Policy{FreeAttempts: 0, BaseLockout: time.Minute, MaxLockout: time.Minute, Window: time.Hour})
+	l.maxEntries = 3
+
+	// every key is locked after one attempt, unlock ip:1 so it gets evicted first
+	l.Reserve("ip:0")
+	l.Reserve("ip:1")
+	l.Reserve("ip:2")
+	l.entries["ip:1"].lockedUntil = time.Time{}
+
+	l.Reserve("ip:3")
+	if _, ok := l.entries["ip:1"]; ok || len(l.entries) != 3 {
+		t.Fatalf("entries = %v, want ip:1 evicted", l.entries)
+	}
+
+	// only locked keys left, the map still doesn't grow
+	for i := 4; i < 20; i++ {
+		l.Reserve(fmt.Sprintf("ip:%d", i))
+		if len(l.entries) > 3 {
+			t.Fatalf("%d entries, max is 3", len(l.entries))
+		}
+	}
+}
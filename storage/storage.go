@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// Storage saves uploaded files (cafe photos). The local backend is for
+// development and single server setups, S3 works with AWS, MinIO, R2 etc.
+//
+// keys look like "photos/<random>.jpg", always with "/" as separator
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+
+	// URL is the public address of the file, saved with the photo row
+	URL(key string) string
+}
+
+// FromEnv picks the storage from the STORAGE variable in .env
+//
+//	STORAGE=s3 -> S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY, S3_PUBLIC_URL
+//	anything else -> local folder UPLOAD_DIR (default "uploads"), served at UPLOAD_BASE_URL
+func FromEnv() Storage {
+	switch os.Getenv("STORAGE") {
+	case "s3":
+		return &S3Storage{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    envOr("S3_REGION", "us-east-1"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		}
+	default:
+		return &LocalStorage{
+			Dir:     envOr("UPLOAD_DIR", "uploads"),
+			BaseURL: envOr("UPLOAD_BASE_URL", "http://localhost:8080/uploads"),
+		}
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}